@@ -0,0 +1,94 @@
+package couchcandy
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Compact Starts the compaction of the database. CouchDB runs the
+// compaction in the background, the response only acknowledges the request.
+func (db *DB) Compact() (*OperationResponse, error) {
+	url := fmt.Sprintf("%s/_compact", createDatabaseURL(db.session))
+	return db.postOperation(url, "")
+}
+
+// CompactViews Starts the compaction of the view indexes of the passed design
+// document. The ddoc is the design document name without the "_design/" prefix.
+func (db *DB) CompactViews(ddoc string) (*OperationResponse, error) {
+	url := fmt.Sprintf("%s/_compact/%s", createDatabaseURL(db.session), escapeSegment(strings.TrimPrefix(ddoc, designPrefix)))
+	return db.postOperation(url, "")
+}
+
+// ViewCleanup Removes the view index files that are no longer required by
+// any design document of the database.
+func (db *DB) ViewCleanup() (*OperationResponse, error) {
+	url := fmt.Sprintf("%s/_view_cleanup", createDatabaseURL(db.session))
+	return db.postOperation(url, "")
+}
+
+// EnsureFullCommit Asks CouchDB to commit any recent changes of the
+// database to disk.
+func (db *DB) EnsureFullCommit() (*CommitResponse, error) {
+
+	url := fmt.Sprintf("%s/_ensure_full_commit", createDatabaseURL(db.session))
+	page, err := db.postJSON(url, "")
+	if err != nil {
+		return nil, err
+	}
+
+	response := &CommitResponse{}
+	unmarshallError := json.Unmarshal(page, response)
+	return response, unmarshallError
+
+}
+
+// GetRevsLimit Returns the maximum number of revisions tracked for each
+// document of the database.
+func (db *DB) GetRevsLimit() (int, error) {
+	url := fmt.Sprintf("%s/_revs_limit", createDatabaseURL(db.session))
+	return db.getLimit(url)
+}
+
+// SetRevsLimit Sets the maximum number of revisions tracked for each
+// document of the database.
+func (db *DB) SetRevsLimit(limit int) (*OperationResponse, error) {
+	url := fmt.Sprintf("%s/_revs_limit", createDatabaseURL(db.session))
+	return db.putOperation(url, strconv.Itoa(limit))
+}
+
+// Purge Permanently removes the passed revisions, keyed by document id, from
+// the database. Unlike a deletion, a purge leaves no tombstone behind.
+func (db *DB) Purge(revisions map[string][]string) (*PurgeResponse, error) {
+
+	url := fmt.Sprintf("%s/_purge", createDatabaseURL(db.session))
+	body, marshallError := json.Marshal(revisions)
+	if marshallError != nil {
+		return nil, marshallError
+	}
+
+	page, err := db.postJSON(url, string(body))
+	if err != nil {
+		return nil, err
+	}
+
+	response := &PurgeResponse{}
+	unmarshallError := json.Unmarshal(page, response)
+	return response, unmarshallError
+
+}
+
+// GetPurgedInfosLimit Returns the number of purge requests the database
+// keeps track of.
+func (db *DB) GetPurgedInfosLimit() (int, error) {
+	url := fmt.Sprintf("%s/_purged_infos_limit", createDatabaseURL(db.session))
+	return db.getLimit(url)
+}
+
+// SetPurgedInfosLimit Sets the number of purge requests the database
+// keeps track of.
+func (db *DB) SetPurgedInfosLimit(limit int) (*OperationResponse, error) {
+	url := fmt.Sprintf("%s/_purged_infos_limit", createDatabaseURL(db.session))
+	return db.putOperation(url, strconv.Itoa(limit))
+}
@@ -0,0 +1,307 @@
+package couchcandy
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Checkpoint Stores the sequence of the changes feed an incremental backup
+// reached. Load returns an empty sequence when none was saved yet.
+type Checkpoint interface {
+	Load() (string, error)
+	Save(seq string) error
+}
+
+// BackupOptions Options available when backing up a database.
+// Checkpoint : where the sequence reached is loaded from and saved to, required
+// Attachments, DesignDocs, Security : as for Dump, the security object being part of full backups only
+// BatchSize : number of documents or changes read per request, DefaultBatchSize when 0
+type BackupOptions struct {
+	Checkpoint  Checkpoint
+	Attachments bool
+	DesignDocs  bool
+	Security    bool
+	BatchSize   int
+}
+
+// BackupResult The outcome of a backup. Full tells whether the backup is a
+// full dump or an increment. Documents counts the documents written, of
+// which Deleted were deleted, and Seq is the sequence saved in the checkpoint.
+type BackupResult struct {
+	Full      bool
+	Documents int
+	Deleted   int
+	Seq       string
+}
+
+// Backup Writes an incremental backup of the database to w : a full dump
+// when the checkpoint holds no sequence yet, the documents changed or
+// deleted since the sequence otherwise. The sequence reached is saved once
+// the backup is written, so a failed backup is simply done again by the next
+// run. The increments are in the format of the dumps, deleted documents
+// being written with _deleted, see RestoreBackup. All the requests of the
+// backup are sent with the context.
+func (db *DB) Backup(ctx context.Context, w io.Writer, options BackupOptions) (*BackupResult, error) {
+
+	if options.Checkpoint == nil {
+		return nil, errors.New("backup: no checkpoint")
+	}
+	db = db.WithContext(ctx)
+
+	since, err := options.Checkpoint.Load()
+	if err != nil {
+		return nil, err
+	}
+
+	var result *BackupResult
+	if since == "" {
+		var dump *DumpResult
+		dump, err = db.Dump(w, DumpOptions{
+			Attachments: options.Attachments,
+			DesignDocs:  options.DesignDocs,
+			Security:    options.Security,
+			BatchSize:   options.BatchSize,
+		})
+		if dump != nil {
+			result = &BackupResult{Full: true, Documents: dump.Documents, Seq: dump.Seq}
+		}
+	} else {
+		result, err = db.backupChanges(ctx, w, since, options)
+	}
+	if err != nil {
+		return result, err
+	}
+
+	return result, options.Checkpoint.Save(result.Seq)
+
+}
+
+// backupChanges writes the documents changed since the sequence, a batch of
+// changes at a time.
+func (db *DB) backupChanges(ctx context.Context, w io.Writer, since string, options BackupOptions) (*BackupResult, error) {
+
+	result := &BackupResult{Seq: since}
+
+	batchSize := options.BatchSize
+	if batchSize <= 0 {
+		batchSize = DefaultBatchSize
+	}
+
+	for {
+
+		changes, errs := db.Changes(ctx, ChangesOptions{Since: result.Seq, Limit: batchSize})
+
+		count := 0
+		docs := make([]BulkGetDoc, 0, batchSize)
+		indexes := make(map[string]int)
+		lastSeq := ""
+		for change := range changes {
+
+			count++
+			lastSeq = change.Seq
+			if !options.DesignDocs && strings.HasPrefix(change.ID, designPrefix) {
+				continue
+			}
+
+			// A deleted document is read at the revision of its tombstone,
+			// the others at their current revision.
+			doc := BulkGetDoc{ID: change.ID}
+			if change.Deleted && len(change.Changes) > 0 {
+				doc.Rev = change.Changes[0].Rev
+			}
+			if index, ok := indexes[change.ID]; ok {
+				docs[index] = doc
+				continue
+			}
+			indexes[change.ID] = len(docs)
+			docs = append(docs, doc)
+
+		}
+		if err := <-errs; err != nil {
+			return result, err
+		}
+
+		if len(docs) != 0 {
+			written, deleted, err := db.dumpDocs(w, docs, options.Attachments)
+			result.Documents += written
+			result.Deleted += deleted
+			if err != nil {
+				return result, err
+			}
+		}
+
+		if lastSeq != "" {
+			result.Seq = lastSeq
+		}
+		if count < batchSize {
+			return result, nil
+		}
+
+	}
+
+}
+
+// RestoreBackup Restores the dumps of an incremental backup, the full dump
+// followed by the increments in the order they were taken.
+func (db *DB) RestoreBackup(dumps []io.Reader, options RestoreOptions) (*RestoreResult, error) {
+
+	result := &RestoreResult{}
+	for _, dump := range dumps {
+		restored, err := db.Restore(dump, options)
+		if restored != nil {
+			result.Documents += restored.Documents
+			result.Failed = append(result.Failed, restored.Failed...)
+		}
+		if err != nil {
+			return result, err
+		}
+	}
+	return result, nil
+
+}
+
+// updateSeq returns the current update sequence of the database, a string
+// since CouchDB 2.0 and a number before.
+func (db *DB) updateSeq() (string, error) {
+
+	info := &struct {
+		UpdateSeq json.RawMessage `json:"update_seq"`
+	}{}
+	if err := db.getJSON(createDatabaseURL(db.session), info); err != nil {
+		return "", err
+	}
+
+	var seq string
+	if err := json.Unmarshal(info.UpdateSeq, &seq); err != nil {
+		return string(info.UpdateSeq), nil
+	}
+	return seq, nil
+
+}
+
+// localCheckpoint is a checkpoint stored in a _local document.
+type localCheckpoint struct {
+	db *DB
+	id string
+}
+
+// LocalCheckpoint Returns a checkpoint stored in the _local document of the
+// database with the specified id, which is not replicated and so is not
+// restored with the backups.
+func (db *DB) LocalCheckpoint(id string) Checkpoint {
+	return &localCheckpoint{db: db, id: id}
+}
+
+// checkpointDocument is the content of a checkpoint, in a _local document or
+// a file.
+type checkpointDocument struct {
+	ID     string `json:"_id,omitempty"`
+	REV    string `json:"_rev,omitempty"`
+	Seq    string `json:"seq"`
+	Error  string `json:"error,omitempty"`
+	Reason string `json:"reason,omitempty"`
+}
+
+func (c *localCheckpoint) load() (*checkpointDocument, error) {
+
+	checkpoint := &checkpointDocument{}
+	if err := c.db.LocalDocument(c.id, checkpoint); err != nil {
+		return nil, err
+	}
+
+	switch checkpoint.Error {
+	case "":
+		return checkpoint, nil
+	case "not_found":
+		return &checkpointDocument{}, nil
+	default:
+		return nil, errors.New(checkpoint.Error + ": " + checkpoint.Reason)
+	}
+
+}
+
+func (c *localCheckpoint) Load() (string, error) {
+
+	checkpoint, err := c.load()
+	if err != nil {
+		return "", err
+	}
+	return checkpoint.Seq, nil
+
+}
+
+func (c *localCheckpoint) Save(seq string) error {
+
+	checkpoint, err := c.load()
+	if err != nil {
+		return err
+	}
+
+	response, err := c.db.PutLocal(c.id, &checkpointDocument{REV: checkpoint.REV, Seq: seq})
+	if err != nil {
+		return err
+	}
+	if response.Error != "" {
+		return errors.New(response.Error + ": " + response.Reason)
+	}
+	return nil
+
+}
+
+// fileCheckpoint is a checkpoint stored in a file.
+type fileCheckpoint struct {
+	path string
+}
+
+// FileCheckpoint Returns a checkpoint stored in the JSON file at path, which
+// is created when saved.
+func FileCheckpoint(path string) Checkpoint {
+	return &fileCheckpoint{path: path}
+}
+
+func (c *fileCheckpoint) Load() (string, error) {
+
+	content, err := os.ReadFile(c.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return "", nil
+	}
+	if err != nil {
+		return "", err
+	}
+
+	checkpoint := &checkpointDocument{}
+	err = json.Unmarshal(content, checkpoint)
+	return checkpoint.Seq, err
+
+}
+
+// Save replaces the file in one step, through a temporary file, so that an
+// interrupted save leaves the previous checkpoint.
+func (c *fileCheckpoint) Save(seq string) error {
+
+	content, err := json.Marshal(&checkpointDocument{Seq: seq})
+	if err != nil {
+		return err
+	}
+
+	temporary, err := os.CreateTemp(filepath.Dir(c.path), filepath.Base(c.path)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(temporary.Name())
+
+	if _, err = temporary.Write(append(content, '\n')); err != nil {
+		temporary.Close()
+		return err
+	}
+	if err = temporary.Close(); err != nil {
+		return err
+	}
+	return os.Rename(temporary.Name(), c.path)
+
+}
@@ -0,0 +1,180 @@
+package couchcandy
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// backupServer extends dumpServer with two pages of changes since 3-g1AAAA
+// and tombstones for the deleted documents. It records the bodies sent to
+// _bulk_get for the changes.
+func backupServer(t *testing.T, couchcandy *CouchCandy) (*router, *[]string) {
+
+	server := dumpServer(t, couchcandy)
+	bulkGets := make([]string, 0)
+	dumpBulkGet := server.routes[http.MethodPost+" /lendr/_bulk_get"]
+
+	server.
+		Handle(http.MethodGet, "/lendr/_changes", func(req *http.Request, body []byte) (*http.Response, error) {
+			switch req.URL.Query().Get("since") {
+			case "3-g1AAAA":
+				return statusResponse(http.StatusOK, `{"results":[
+					{"seq":"4-g1AAAA","id":"card-1","changes":[{"rev":"3-c"}]},
+					{"seq":"5-g1AAAA","id":"card-9","changes":[{"rev":"2-z"}],"deleted":true}
+				],"last_seq":"5-g1AAAA"}`), nil
+			case "5-g1AAAA":
+				return statusResponse(http.StatusOK, `{"results":[
+					{"seq":"6-g1AAAA","id":"_design/cards","changes":[{"rev":"2-d"}]}
+				],"last_seq":"6-g1AAAA"}`), nil
+			}
+			return statusResponse(http.StatusBadRequest, `{"error":"bad_request","reason":"unexpected since"}`), nil
+		}).
+		Handle(http.MethodPost, "/lendr/_bulk_get", func(req *http.Request, body []byte) (*http.Response, error) {
+			if !strings.Contains(string(body), "card-9") {
+				return dumpBulkGet(req, body)
+			}
+			bulkGets = append(bulkGets, string(body))
+			return statusResponse(http.StatusOK, `{"results":[
+				{"id":"card-1","docs":[{"ok":{"_id":"card-1","_rev":"3-c","_revisions":{"start":3,"ids":["c","b","a"]}}}]},
+				{"id":"card-9","docs":[{"ok":{"_id":"card-9","_rev":"2-z","_deleted":true,"_revisions":{"start":2,"ids":["z","y"]}}}]}
+			]}`), nil
+		})
+	return server, &bulkGets
+
+}
+
+func TestBackup(t *testing.T) {
+
+	couchcandy := newTestClient()
+	server, bulkGets := backupServer(t, couchcandy)
+	path := filepath.Join(t.TempDir(), "lendr.checkpoint")
+	options := BackupOptions{Checkpoint: FileCheckpoint(path), BatchSize: 2}
+
+	full := &bytes.Buffer{}
+	result, err := couchcandy.DB("lendr").Backup(context.Background(), full, options)
+	assert.Nil(t, err)
+	assert.Equal(t, &BackupResult{Full: true, Documents: 2, Seq: "3-g1AAAA"}, result)
+	assert.Equal(t, 2, strings.Count(full.String(), "\n"))
+
+	content, err := os.ReadFile(path)
+	assert.Nil(t, err)
+	assert.JSONEq(t, `{"seq":"3-g1AAAA"}`, string(content))
+
+	server.Reset()
+	increment := &bytes.Buffer{}
+	result, err = couchcandy.DB("lendr").Backup(context.Background(), increment, options)
+	assert.Nil(t, err)
+	assert.Equal(t, &BackupResult{Documents: 2, Deleted: 1, Seq: "6-g1AAAA"}, result)
+	assert.Equal(t, []string{
+		"GET /lendr/_changes?feed=normal&since=3-g1AAAA&limit=2",
+		"POST /lendr/_bulk_get?revs=true",
+		"GET /lendr/_changes?feed=normal&since=5-g1AAAA&limit=2",
+	}, server.Requests())
+	assert.Equal(t, []string{`{"docs":[{"id":"card-1"},{"id":"card-9","rev":"2-z"}]}`}, *bulkGets)
+	assert.Equal(t, `{"_id":"card-1","_rev":"3-c","_revisions":{"start":3,"ids":["c","b","a"]}}`+"\n"+
+		`{"_id":"card-9","_rev":"2-z","_deleted":true,"_revisions":{"start":2,"ids":["z","y"]}}`+"\n", increment.String())
+
+	seq, err := FileCheckpoint(path).Load()
+	assert.Nil(t, err)
+	assert.Equal(t, "6-g1AAAA", seq)
+
+	_, err = couchcandy.DB("lendr").Backup(context.Background(), increment, BackupOptions{})
+	assert.NotNil(t, err)
+
+}
+
+func TestBackupDeletedNotFound(t *testing.T) {
+
+	couchcandy := newTestClient()
+	route(couchcandy).
+		Handle(http.MethodGet, "/lendr/_changes", func(req *http.Request, body []byte) (*http.Response, error) {
+			assert.Equal(t, "span", req.Context().Value(spanKey{}))
+			return statusResponse(http.StatusOK, `{"results":[
+				{"seq":"4-g1AAAA","id":"card-9","changes":[{"rev":"2-z"}],"deleted":true}
+			],"last_seq":"4-g1AAAA"}`), nil
+		}).
+		Handle(http.MethodPost, "/lendr/_bulk_get", func(req *http.Request, body []byte) (*http.Response, error) {
+			assert.Equal(t, "span", req.Context().Value(spanKey{}))
+			return statusResponse(http.StatusOK, `{"results":[
+				{"id":"card-9","docs":[{"error":{"id":"card-9","rev":"2-z","error":"not_found","reason":"missing"}}]}
+			]}`), nil
+		})
+	checkpoint := FileCheckpoint(filepath.Join(t.TempDir(), "lendr.checkpoint"))
+	assert.Nil(t, checkpoint.Save("3-g1AAAA"))
+
+	ctx := context.WithValue(context.Background(), spanKey{}, "span")
+	increment := &bytes.Buffer{}
+	result, err := couchcandy.DB("lendr").Backup(ctx, increment, BackupOptions{Checkpoint: checkpoint})
+	assert.Nil(t, err)
+	assert.Equal(t, &BackupResult{Seq: "4-g1AAAA"}, result)
+	assert.Equal(t, "", increment.String())
+
+}
+
+func TestLocalCheckpoint(t *testing.T) {
+
+	couchcandy := newTestClient()
+	var saved string
+	route(couchcandy).
+		Handle(http.MethodPut, "/lendr/_local/backup", func(req *http.Request, body []byte) (*http.Response, error) {
+			saved = string(body)
+			return statusResponse(http.StatusCreated, `{"ok":true,"id":"_local/backup","rev":"0-2"}`), nil
+		}).
+		Handle(http.MethodGet, "/lendr/_local/backup", func(*http.Request, []byte) (*http.Response, error) {
+			if saved == "" {
+				return statusResponse(http.StatusNotFound, `{"error":"not_found","reason":"missing"}`), nil
+			}
+			return statusResponse(http.StatusOK, `{"_id":"_local/backup","_rev":"0-1","seq":"3-g1AAAA"}`), nil
+		})
+
+	checkpoint := couchcandy.DB("lendr").LocalCheckpoint("backup")
+	seq, err := checkpoint.Load()
+	assert.Nil(t, err)
+	assert.Equal(t, "", seq)
+
+	assert.Nil(t, checkpoint.Save("3-g1AAAA"))
+	assert.JSONEq(t, `{"seq":"3-g1AAAA"}`, saved)
+
+	seq, err = checkpoint.Load()
+	assert.Nil(t, err)
+	assert.Equal(t, "3-g1AAAA", seq)
+
+	assert.Nil(t, checkpoint.Save("6-g1AAAA"))
+	assert.JSONEq(t, `{"_rev":"0-1","seq":"6-g1AAAA"}`, saved)
+
+}
+
+func TestRestoreBackup(t *testing.T) {
+
+	couchcandy := newTestClient()
+	ids := make([]string, 0)
+	route(couchcandy).Handle(http.MethodPost, "/lendr/_bulk_docs", func(req *http.Request, body []byte) (*http.Response, error) {
+		request := &struct {
+			Docs []CandyDocument `json:"docs"`
+		}{}
+		assert.Nil(t, json.Unmarshal(body, request))
+		for _, doc := range request.Docs {
+			ids = append(ids, fmt.Sprintf("%s@%s", doc.ID, doc.REV))
+		}
+		return statusResponse(http.StatusCreated, `[]`), nil
+	})
+
+	result, err := couchcandy.DB("lendr").RestoreBackup([]io.Reader{
+		strings.NewReader(`{"_id":"card-1","_rev":"2-b"}` + "\n" + `{"_id":"card-9","_rev":"1-y"}` + "\n"),
+		strings.NewReader(`{"_id":"card-1","_rev":"3-c"}` + "\n" + `{"_id":"card-9","_rev":"2-z","_deleted":true}` + "\n"),
+	}, RestoreOptions{})
+	assert.Nil(t, err)
+	assert.Equal(t, 4, result.Documents)
+	assert.Equal(t, []string{"card-1@2-b", "card-9@1-y", "card-1@3-c", "card-9@2-z"}, ids)
+
+}
@@ -0,0 +1,116 @@
+package couchcandy
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// HeaderAccept is the header telling the content type expected in the response
+const HeaderAccept string = "Accept"
+
+// BulkDocsOptions Options available when writing documents in bulk.
+// KeepRevisions : writes the documents with their _rev and _revisions as
+// they are, like a replication does, instead of creating new revisions
+type BulkDocsOptions struct {
+	KeepRevisions bool
+}
+
+// BulkGetOptions Options available when reading documents in bulk.
+// Revs : includes the revision history of the documents in _revisions
+// Attachments : includes the content of the attachments, base64 encoded
+type BulkGetOptions struct {
+	Revs        bool
+	Attachments bool
+}
+
+// BulkGetDoc Identifies a document to read in bulk, at its current revision
+// when Rev is empty.
+type BulkGetDoc struct {
+	ID  string `json:"id"`
+	Rev string `json:"rev,omitempty"`
+}
+
+// BulkGetResult The revisions read for a requested document. Each of the Docs
+// holds either the document in OK or the reason it could not be read in Error.
+type BulkGetResult struct {
+	ID   string        `json:"id"`
+	Docs []BulkGetItem `json:"docs"`
+}
+
+// BulkGetItem A revision of a BulkGetResult. OK is a json.RawMessage so that
+// it can be unmarshaled with the desired type.
+type BulkGetItem struct {
+	OK    json.RawMessage    `json:"ok,omitempty"`
+	Error *OperationResponse `json:"error,omitempty"`
+}
+
+// BulkDocs Writes the documents in a single request. The returned responses
+// tell the outcome of each document, in their order, a document that fails
+// does not prevent the others from being written. When the revisions are
+// kept, CouchDB only reports the documents that failed.
+func (db *DB) BulkDocs(documents interface{}, options BulkDocsOptions) ([]OperationResponse, error) {
+
+	request := struct {
+		Docs     interface{} `json:"docs"`
+		NewEdits *bool       `json:"new_edits,omitempty"`
+	}{Docs: documents}
+	if options.KeepRevisions {
+		request.NewEdits = new(bool)
+	}
+
+	body, marshallError := json.Marshal(request)
+	if marshallError != nil {
+		return nil, marshallError
+	}
+
+	page, err := db.postJSON(fmt.Sprintf("%s/_bulk_docs", createDatabaseURL(db.session)), string(body))
+	if err != nil {
+		return nil, err
+	}
+
+	if couchError := toCouchError(page); couchError != nil {
+		return nil, couchError
+	}
+
+	responses := make([]OperationResponse, 0)
+	unmarshallError := json.Unmarshal(page, &responses)
+	return responses, unmarshallError
+
+}
+
+// BulkGet Reads the documents in a single request, in the order of docs.
+func (db *DB) BulkGet(docs []BulkGetDoc, options BulkGetOptions) ([]BulkGetResult, error) {
+
+	parameters := []string{fmt.Sprintf("revs=%v", options.Revs)}
+	if options.Attachments {
+		parameters = append(parameters, "attachments=true")
+	}
+	url := fmt.Sprintf("%s/_bulk_get?%s", createDatabaseURL(db.session), strings.Join(parameters, "&"))
+
+	body, marshallError := json.Marshal(map[string][]BulkGetDoc{"docs": docs})
+	if marshallError != nil {
+		return nil, marshallError
+	}
+
+	// The attachments are sent in a multipart response unless JSON is asked for.
+	page, err := db.newRequest(http.MethodPost, url).
+		Header(HeaderAccept, JSONContentType).
+		JSON(string(body)).
+		Read()
+	if err != nil {
+		return nil, err
+	}
+
+	if couchError := toCouchError(page); couchError != nil {
+		return nil, couchError
+	}
+
+	results := &struct {
+		Results []BulkGetResult `json:"results"`
+	}{}
+	unmarshallError := json.Unmarshal(page, results)
+	return results.Results, unmarshallError
+
+}
@@ -0,0 +1,118 @@
+package couchcandy
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/url"
+	"strings"
+)
+
+// ChangesOptions Options available when following the _changes feed of a
+// database.
+// Feed : one of FeedNormal, FeedLongPoll or FeedContinuous, FeedNormal when empty
+// Since : the sequence to start from, "now" to only get the coming changes
+// Timeout : milliseconds to wait for a change before closing a request
+// Heartbeat : milliseconds after which an empty line is sent to keep the connection alive
+// IncludeDocs : includes the documents in the changes
+// Conflicts : includes the conflicting revisions with the documents
+// Style : "all_docs" to get all the leaf revisions instead of the winning one
+// Limit : number of changes per request
+// Filter : the filter function, like "cards/by_suit", or "_design" or "_doc_ids"
+// DocIDs : the ids of the documents to follow, with the "_doc_ids" filter
+type ChangesOptions struct {
+	Feed        string
+	Since       string
+	Timeout     int
+	Heartbeat   int
+	IncludeDocs bool
+	Conflicts   bool
+	Style       string
+	Limit       int
+	Filter      string
+	DocIDs      []string
+}
+
+// DocumentChange An event of the _changes feed. Doc is only set when the
+// documents are included, as a json.RawMessage so that it can be
+// unmarshaled with the desired type.
+type DocumentChange struct {
+	Seq     string          `json:"seq,omitempty"`
+	ID      string          `json:"id,omitempty"`
+	Changes []Change        `json:"changes,omitempty"`
+	Deleted bool            `json:"deleted,omitempty"`
+	Doc     json.RawMessage `json:"doc,omitempty"`
+}
+
+// Changes Follows the changes of the documents of the database. The changes
+// are sent on the returned channel, which is closed when the feed ends :
+// after the current changes in normal mode, when the context is done in
+// longpoll and continuous modes, or when an error occurs. In that last case
+// the error is sent on the error channel before it is closed.
+func (db *DB) Changes(ctx context.Context, options ChangesOptions) (<-chan DocumentChange, <-chan error) {
+
+	changes := make(chan DocumentChange)
+	errs := make(chan error, 1)
+
+	go func() {
+
+		defer close(errs)
+		defer close(changes)
+
+		open := func(ctx context.Context, since string) (io.ReadCloser, error) {
+			options.Since = since
+			url := fmt.Sprintf("%s/_changes%s", createDatabaseURL(db.session), toChangesQueryString(options))
+			return db.streamFeed(ctx, url)
+		}
+
+		err := followFeed(ctx, options.Feed, options.Since, open, changes)
+		if err != nil {
+			errs <- err
+		}
+
+	}()
+
+	return changes, errs
+
+}
+
+func toChangesQueryString(options ChangesOptions) string {
+
+	feed := options.Feed
+	if feed == "" {
+		feed = FeedNormal
+	}
+
+	parameters := []string{fmt.Sprintf("feed=%s", feed)}
+	if options.Since != "" {
+		parameters = append(parameters, fmt.Sprintf("since=%s", url.QueryEscape(options.Since)))
+	}
+	if options.Timeout != 0 {
+		parameters = append(parameters, fmt.Sprintf("timeout=%d", options.Timeout))
+	}
+	if options.Heartbeat != 0 {
+		parameters = append(parameters, fmt.Sprintf("heartbeat=%d", options.Heartbeat))
+	}
+	if options.IncludeDocs {
+		parameters = append(parameters, "include_docs=true")
+	}
+	if options.Conflicts {
+		parameters = append(parameters, "conflicts=true")
+	}
+	if options.Style != "" {
+		parameters = append(parameters, fmt.Sprintf("style=%s", url.QueryEscape(options.Style)))
+	}
+	if options.Limit != 0 {
+		parameters = append(parameters, fmt.Sprintf("limit=%d", options.Limit))
+	}
+	if options.Filter != "" {
+		parameters = append(parameters, fmt.Sprintf("filter=%s", url.QueryEscape(options.Filter)))
+	}
+	if len(options.DocIDs) != 0 {
+		ids, _ := json.Marshal(options.DocIDs)
+		parameters = append(parameters, fmt.Sprintf("doc_ids=%s", url.QueryEscape(string(ids))))
+	}
+	return "?" + strings.Join(parameters, "&")
+
+}
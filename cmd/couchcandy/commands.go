@@ -0,0 +1,549 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/spacemojo/couchcandy"
+)
+
+// followHeartbeat keeps a followed feed alive, in milliseconds.
+const followHeartbeat = 30000
+
+func runDBs(c *cli, args []string) error {
+
+	if err := parse(c.flagSet("dbs"), args, 0, 0); err != nil {
+		return err
+	}
+
+	names, err := c.client.Server().AllDatabases()
+	if err != nil {
+		return err
+	}
+
+	return c.print(names, func() table {
+		t := table{header: []string{"NAME"}}
+		for _, name := range names {
+			t.rows = append(t.rows, []string{name})
+		}
+		return t
+	})
+
+}
+
+func runInfo(c *cli, args []string) error {
+
+	fs := c.flagSet("info")
+	if err := parse(fs, args, 0, 1); err != nil {
+		return err
+	}
+
+	db, err := c.db(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+
+	results, err := c.client.Server().DBsInfo([]string{db.Name()})
+	if err != nil {
+		return err
+	}
+	if len(results) == 0 || results[0].Info == nil {
+		return fmt.Errorf("%s: database does not exist", db.Name())
+	}
+
+	info := results[0].Info
+	return c.print(info, func() table {
+		body, _ := json.Marshal(info)
+		return fieldsTable(body)
+	})
+
+}
+
+func runGet(c *cli, args []string) error {
+
+	fs := c.flagSet("get")
+	rev := fs.String("rev", "", "revision to get, the current one by default")
+	if err := parse(fs, args, 1, 1); err != nil {
+		return err
+	}
+
+	db, err := c.db("")
+	if err != nil {
+		return err
+	}
+
+	var document json.RawMessage
+	if err = db.Document(fs.Arg(0), &document, couchcandy.Options{Rev: *rev}); err != nil {
+		return err
+	}
+	if err = checkDocument(document); err != nil {
+		return err
+	}
+
+	return c.print(document, func() table { return fieldsTable(document) })
+
+}
+
+func runPut(c *cli, args []string) error {
+
+	fs := c.flagSet("put")
+	if err := parse(fs, args, 1, 2); err != nil {
+		return err
+	}
+
+	db, err := c.db("")
+	if err != nil {
+		return err
+	}
+
+	body, err := c.readInput(fs.Arg(1))
+	if err != nil {
+		return err
+	}
+	if !json.Valid(body) {
+		return fmt.Errorf("the document is not valid JSON")
+	}
+
+	response, err := checkOperation(db.AddWithID(fs.Arg(0), json.RawMessage(body)))
+	if err != nil {
+		return err
+	}
+	return c.print(response, operationTable(response))
+
+}
+
+func runDelete(c *cli, args []string) error {
+
+	fs := c.flagSet("delete")
+	rev := fs.String("rev", "", "revision to delete, the current one by default")
+	if err := parse(fs, args, 1, 1); err != nil {
+		return err
+	}
+
+	db, err := c.db("")
+	if err != nil {
+		return err
+	}
+
+	id := fs.Arg(0)
+	if *rev == "" {
+		if *rev, err = db.Revision(id); err != nil {
+			return err
+		}
+	}
+
+	response, err := checkOperation(db.DeleteDocument(id, *rev))
+	if err != nil {
+		return err
+	}
+	return c.print(response, operationTable(response))
+
+}
+
+func runView(c *cli, args []string) error {
+
+	fs := c.flagSet("view")
+	options := couchcandy.Options{}
+	fs.StringVar(&options.Key, "key", "", "key to match, as JSON")
+	fs.StringVar(&options.Keys, "keys", "", "keys to match, as a JSON array")
+	fs.StringVar(&options.StartKey, "start-key", "", "first key, as JSON")
+	fs.StringVar(&options.EndKey, "end-key", "", "last key, as JSON")
+	fs.IntVar(&options.Limit, "limit", 0, "number of rows")
+	fs.IntVar(&options.Skip, "skip", 0, "number of rows to skip")
+	fs.BoolVar(&options.Descending, "descending", false, "reverses the order of the rows")
+	fs.BoolVar(&options.Reduce, "reduce", false, "runs the reduce function")
+	fs.IntVar(&options.GroupLevel, "group-level", 0, "groups the reduced rows by the first elements of their keys")
+	if err := parse(fs, args, 2, 2); err != nil {
+		return err
+	}
+
+	db, err := c.db("")
+	if err != nil {
+		return err
+	}
+
+	response, err := db.View(fs.Arg(0), fs.Arg(1), options)
+	if err != nil {
+		return err
+	}
+	if response.Error != "" {
+		return fmt.Errorf("%s: %s", response.Error, response.Reason)
+	}
+
+	return c.print(response, func() table {
+		t := table{header: []string{"ID", "KEY", "VALUE"}}
+		for _, row := range response.Rows {
+			t.rows = append(t.rows, []string{row.ID, cell(row.Key), cell(row.Value)})
+		}
+		return t
+	})
+
+}
+
+func runFind(c *cli, args []string) error {
+
+	fs := c.flagSet("find")
+	fields := fs.String("fields", "", "comma separated fields to return, the columns of the table")
+	sort := fs.String("sort", "", "sort order, as a JSON array")
+	query := couchcandy.FindQuery{}
+	fs.IntVar(&query.Limit, "limit", 0, "number of documents")
+	fs.IntVar(&query.Skip, "skip", 0, "number of documents to skip")
+	fs.StringVar(&query.Bookmark, "bookmark", "", "bookmark of the page to get")
+	if err := parse(fs, args, 1, 1); err != nil {
+		return err
+	}
+
+	db, err := c.db("")
+	if err != nil {
+		return err
+	}
+
+	selector, err := c.readArgument(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	if !json.Valid(selector) {
+		return fmt.Errorf("the selector is not valid JSON")
+	}
+	query.Selector = json.RawMessage(selector)
+	query.Fields = splitList(*fields)
+	if *sort != "" {
+		if err = json.Unmarshal([]byte(*sort), &query.Sort); err != nil {
+			return fmt.Errorf("invalid sort: %v", err)
+		}
+	}
+
+	response, err := db.Find(query)
+	if err != nil {
+		return err
+	}
+	if response.Error != "" {
+		return fmt.Errorf("%s: %s", response.Error, response.Reason)
+	}
+
+	return c.print(response, func() table {
+		columns := query.Fields
+		if len(columns) == 0 {
+			columns = []string{"_id", "_rev"}
+		}
+		t := table{header: make([]string, len(columns))}
+		for i, column := range columns {
+			t.header[i] = strings.ToUpper(column)
+		}
+		for _, doc := range response.Docs {
+			values := make(map[string]json.RawMessage)
+			_ = json.Unmarshal(doc, &values)
+			row := make([]string, len(columns))
+			for i, column := range columns {
+				if value, ok := values[column]; ok {
+					row[i] = cell(value)
+				}
+			}
+			t.rows = append(t.rows, row)
+		}
+		return t
+	})
+
+}
+
+// runChanges prints the changes as they come, one JSON object per line in
+// the JSON format.
+func runChanges(c *cli, args []string) error {
+
+	fs := c.flagSet("changes")
+	follow := fs.Bool("follow", false, "keeps printing the changes as they happen, until interrupted")
+	options := couchcandy.ChangesOptions{}
+	fs.StringVar(&options.Since, "since", "", "sequence to start from, now for the coming changes only")
+	fs.BoolVar(&options.IncludeDocs, "include-docs", false, "includes the documents")
+	fs.IntVar(&options.Limit, "limit", 0, "number of changes")
+	fs.StringVar(&options.Filter, "filter", "", "filter function, like ddoc/filter")
+	if err := parse(fs, args, 0, 0); err != nil {
+		return err
+	}
+
+	db, err := c.db("")
+	if err != nil {
+		return err
+	}
+
+	if *follow {
+		options.Feed = couchcandy.FeedContinuous
+		options.Heartbeat = followHeartbeat
+	}
+
+	// The table is flushed after every line when following, as the feed
+	// never ends.
+	tw := newTabWriter(c.stdout)
+	out := io.Writer(c.stdout)
+	flush := func() error {
+		if *follow {
+			return tw.Flush()
+		}
+		return nil
+	}
+	if c.format == formatTable {
+		out = tw
+		fmt.Fprintln(tw, "SEQ\tID\tREV\tDELETED")
+		if err = flush(); err != nil {
+			return err
+		}
+	}
+
+	changes, errs := db.Changes(c.ctx, options)
+	for change := range changes {
+		if err = c.printChange(out, change); err != nil {
+			return err
+		}
+		if err = flush(); err != nil {
+			return err
+		}
+	}
+	if err = tw.Flush(); err != nil {
+		return err
+	}
+	return <-errs
+
+}
+
+func (c *cli) printChange(w io.Writer, change couchcandy.DocumentChange) error {
+
+	if c.format == formatJSON {
+		body, err := json.Marshal(change)
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Fprintf(w, "%s\n", body)
+		return err
+	}
+
+	revs := make([]string, len(change.Changes))
+	for i, revision := range change.Changes {
+		revs[i] = revision.Rev
+	}
+	_, err := fmt.Fprintf(w, "%s\t%s\t%s\t%t\n", change.Seq, change.ID, strings.Join(revs, ","), change.Deleted)
+	return err
+
+}
+
+func runAttach(c *cli, args []string) error {
+
+	fs := c.flagSet("attach")
+	name := fs.String("name", "", "name of the attachment, the file name by default")
+	contentType := fs.String("type", "", "content type, guessed from the file extension by default")
+	rev := fs.String("rev", "", "revision of the document, the current one by default")
+	if err := parse(fs, args, 2, 2); err != nil {
+		return err
+	}
+
+	db, err := c.db("")
+	if err != nil {
+		return err
+	}
+
+	id, path := fs.Arg(0), fs.Arg(1)
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	if *name == "" {
+		*name = filepath.Base(path)
+	}
+	if *contentType == "" {
+		*contentType = mime.TypeByExtension(filepath.Ext(path))
+	}
+	if *contentType == "" {
+		*contentType = "application/octet-stream"
+	}
+	if *rev == "" {
+		if *rev, err = db.Revision(id); err != nil {
+			return err
+		}
+	}
+
+	response, err := checkOperation(db.AddAttachment(id, *rev, *name, *contentType, content))
+	if err != nil {
+		return err
+	}
+	return c.print(response, operationTable(response))
+
+}
+
+func runDDoc(c *cli, args []string) error {
+
+	if len(args) > 0 {
+		switch args[0] {
+		case "push":
+			return c.pushDesignDocs(args[1:])
+		case "pull":
+			return c.pullDesignDocs(args[1:])
+		}
+	}
+	c.flagSet("ddoc").Usage()
+	return errUsage
+
+}
+
+// pushDesignDocs synchronises the design documents of the database with the
+// directory, laid out as couchcandy.LoadDesignDocs reads it.
+func (c *cli) pushDesignDocs(args []string) error {
+
+	fs := c.flagSet("ddoc")
+	staged := fs.Bool("staged", false, "builds the changed indexes before switching to them")
+	if err := parse(fs, args, 1, 1); err != nil {
+		return err
+	}
+
+	db, err := c.db("")
+	if err != nil {
+		return err
+	}
+
+	designDocs, err := couchcandy.LoadDesignDocs(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+
+	sync := db.DesignDocSync()
+	sync.Staged = *staged
+	result, err := sync.Sync(c.ctx, designDocs)
+	if err != nil {
+		return err
+	}
+
+	return c.print(result, func() table {
+		t := table{header: []string{"ID", "STATUS"}}
+		for status, ids := range [][]string{result.Created, result.Updated, result.Unchanged} {
+			for _, id := range ids {
+				t.rows = append(t.rows, []string{id, []string{"created", "updated", "unchanged"}[status]})
+			}
+		}
+		return t
+	})
+
+}
+
+// pullDesignDocs writes the design documents, all of them or the named ones,
+// to a "name.json" file each, without their revision so that they can be
+// pushed back.
+func (c *cli) pullDesignDocs(args []string) error {
+
+	fs := c.flagSet("ddoc")
+	if err := parse(fs, args, 1, -1); err != nil {
+		return err
+	}
+
+	db, err := c.db("")
+	if err != nil {
+		return err
+	}
+
+	var designDocs []*couchcandy.DesignDocument
+	if fs.NArg() == 1 {
+		if designDocs, err = db.DesignDocs(); err != nil {
+			return err
+		}
+	}
+	for _, name := range fs.Args()[1:] {
+		designDoc, err := db.DesignDocument(name)
+		if err != nil {
+			return err
+		}
+		designDocs = append(designDocs, designDoc)
+	}
+
+	dir := fs.Arg(0)
+	if err = os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+
+	files := make(map[string]string, len(designDocs))
+	for _, designDoc := range designDocs {
+
+		designDoc.REV = ""
+		body, err := json.MarshalIndent(designDoc, "", "  ")
+		if err != nil {
+			return err
+		}
+
+		name := strings.ReplaceAll(strings.TrimPrefix(designDoc.ID, "_design/"), "/", "_")
+		path := filepath.Join(dir, name+".json")
+		if err = os.WriteFile(path, append(body, '\n'), 0644); err != nil {
+			return err
+		}
+		files[designDoc.ID] = path
+
+	}
+
+	return c.print(files, func() table {
+		t := table{header: []string{"ID", "FILE"}}
+		for _, designDoc := range designDocs {
+			t.rows = append(t.rows, []string{designDoc.ID, files[designDoc.ID]})
+		}
+		return t
+	})
+
+}
+
+func runReplicate(c *cli, args []string) error {
+
+	fs := c.flagSet("replicate")
+	replication := couchcandy.ReplicationRequest{}
+	fs.BoolVar(&replication.Continuous, "continuous", false, "keeps replicating the coming changes")
+	fs.BoolVar(&replication.CreateTarget, "create-target", false, "creates the target database when missing")
+	fs.BoolVar(&replication.Cancel, "cancel", false, "cancels the continuous replication")
+	fs.StringVar(&replication.Filter, "filter", "", "filter function, like ddoc/filter")
+	docIDs := fs.String("doc-ids", "", "comma separated ids of the documents to replicate")
+	selector := fs.String("selector", "", "selector of the documents to replicate, as JSON")
+	if err := parse(fs, args, 2, 2); err != nil {
+		return err
+	}
+
+	replication.Source, replication.Target = fs.Arg(0), fs.Arg(1)
+	replication.DocIDs = splitList(*docIDs)
+	if *selector != "" {
+		if !json.Valid([]byte(*selector)) {
+			return fmt.Errorf("the selector is not valid JSON")
+		}
+		replication.Selector = json.RawMessage(*selector)
+	}
+
+	response, err := c.client.Server().Replicate(replication)
+	if err != nil {
+		return err
+	}
+
+	return c.print(response, func() table {
+		return table{
+			header: []string{"OK", "SESSION", "SOURCE LAST SEQ"},
+			rows:   [][]string{{strconv.FormatBool(response.OK), response.SessionID, response.SourceLastSeq}},
+		}
+	})
+
+}
+
+// readInput returns the content of the file, or of stdin when the path is
+// empty or "-".
+func (c *cli) readInput(path string) ([]byte, error) {
+
+	if path == "" || path == "-" {
+		return io.ReadAll(c.stdin)
+	}
+	return os.ReadFile(path)
+
+}
+
+// readArgument returns the argument, or the content of stdin when it is "-".
+func (c *cli) readArgument(arg string) ([]byte, error) {
+
+	if arg == "-" {
+		return io.ReadAll(c.stdin)
+	}
+	return []byte(arg), nil
+
+}
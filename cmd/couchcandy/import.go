@@ -0,0 +1,112 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"unicode/utf8"
+
+	"github.com/spacemojo/couchcandy"
+)
+
+// importFormats maps the file extensions to the import formats.
+var importFormats = map[string]string{
+	".csv":    couchcandy.ImportCSV,
+	".ndjson": couchcandy.ImportNDJSON,
+	".jsonl":  couchcandy.ImportNDJSON,
+}
+
+func runImport(c *cli, args []string) error {
+
+	fs := c.flagSet("import")
+	options := couchcandy.ImportOptions{}
+	fs.StringVar(&options.Format, "format", "", "csv or ndjson, guessed from the file extension by default")
+	mapping := fs.String("mapping", "", "YAML or JSON file of the mapping, all the columns as strings by default")
+	id := fs.String("id", "", "template of the document ids, like user:{email}, overrides the mapping")
+	comma := fs.String("comma", ",", "separator of the CSV columns")
+	fs.IntVar(&options.BatchSize, "batch-size", couchcandy.DefaultBatchSize, "documents per request")
+	fs.IntVar(&options.Workers, "workers", couchcandy.DefaultImportWorkers, "requests sent concurrently")
+	fs.BoolVar(&options.Upsert, "upsert", false, "updates the existing documents instead of reporting conflicts")
+	if err := parse(fs, args, 0, 1); err != nil {
+		return err
+	}
+
+	db, err := c.db("")
+	if err != nil {
+		return err
+	}
+
+	if *mapping != "" {
+		loaded, err := couchcandy.LoadImportMapping(*mapping)
+		if err != nil {
+			return err
+		}
+		options.Mapping = *loaded
+	}
+	if *id != "" {
+		options.Mapping.ID = *id
+	}
+	if options.Comma, _ = utf8.DecodeRuneInString(*comma); utf8.RuneCountInString(*comma) != 1 {
+		return fmt.Errorf("the separator must be a single character")
+	}
+
+	path := fs.Arg(0)
+	if options.Format == "" {
+		options.Format = importFormats[filepath.Ext(path)]
+	}
+	if options.Format == "" {
+		return fmt.Errorf("unknown format, set -format")
+	}
+
+	input := c.stdin
+	if path != "" && path != "-" {
+		file, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		input = file
+	}
+
+	result, err := db.Import(input, options)
+	if result != nil {
+		if printError := c.printImport(result); printError != nil {
+			return printError
+		}
+	}
+	if err != nil {
+		return err
+	}
+	if len(result.Errors) != 0 {
+		return fmt.Errorf("%d of %d rows were not imported", len(result.Errors), result.Rows)
+	}
+	return nil
+
+}
+
+// printImport prints the result, followed by the rows in error in the table
+// format.
+func (c *cli) printImport(result *couchcandy.ImportResult) error {
+
+	if c.format == formatJSON {
+		return c.print(result, nil)
+	}
+
+	err := writeTable(c.stdout, table{
+		header: []string{"ROWS", "WRITTEN", "FAILED"},
+		rows:   [][]string{{strconv.Itoa(result.Rows), strconv.Itoa(result.Written), strconv.Itoa(len(result.Errors))}},
+	})
+	if err != nil || len(result.Errors) == 0 {
+		return err
+	}
+
+	errors := table{header: []string{"ROW", "ID", "ERROR"}}
+	for _, rowError := range result.Errors {
+		errors.rows = append(errors.rows, []string{strconv.Itoa(rowError.Row), rowError.ID, rowError.Reason})
+	}
+	io.WriteString(c.stdout, "\n")
+	return writeTable(c.stdout, errors)
+
+}
@@ -0,0 +1,212 @@
+// Command couchcandy runs the common CouchDB operations from the command
+// line : listing databases, reading and writing documents, querying views
+// and Mango indexes, following changes, pushing design documents and
+// replicating databases, and importing CSV or NDJSON files.
+//
+// The connection settings are loaded by couchcandy.LoadConfig, from the
+// COUCHDB_* environment variables and the file named by COUCHDB_CONFIG. The
+// dbhost, dbname, dbusername and dbpassword variables read by
+// couchcandy.NewDBSession are honoured as well, when the matching COUCHDB_*
+// variable is not set. The flags given before the command override both :
+//
+//	couchcandy -database cards -o table view cards by_suit -limit 10
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"sort"
+	"strings"
+
+	"github.com/spacemojo/couchcandy"
+)
+
+const (
+	// formatJSON prints the responses as indented JSON
+	formatJSON string = "json"
+	// formatTable prints the responses as aligned columns
+	formatTable string = "table"
+)
+
+// errUsage is returned when the command line is invalid, the usage having
+// been printed already.
+var errUsage = errors.New("invalid usage")
+
+// command A subcommand, run with the arguments that follow its name.
+type command struct {
+	usage   string
+	summary string
+	run     func(cli *cli, args []string) error
+}
+
+// commands The subcommands by name, filled by init as they print their own
+// usage.
+var commands map[string]command
+
+func init() {
+	commands = map[string]command{
+		"dbs":       {"dbs", "lists the databases", runDBs},
+		"import":    {"import [options] [file]", "imports the CSV or NDJSON rows of the file, or stdin", runImport},
+		"info":      {"info [database]", "shows the information of the database", runInfo},
+		"get":       {"get [-rev rev] id", "prints the document", runGet},
+		"put":       {"put id [file]", "writes the document read from the file, or stdin", runPut},
+		"delete":    {"delete [-rev rev] id", "deletes the document, at its current revision by default", runDelete},
+		"view":      {"view [options] ddoc view", "queries the view", runView},
+		"find":      {"find [options] selector", "runs the Mango query, the selector being JSON or - for stdin", runFind},
+		"changes":   {"changes [-follow] [options]", "prints the changes of the database", runChanges},
+		"attach":    {"attach [-name name] [-type type] [-rev rev] id file", "attaches the file to the document", runAttach},
+		"ddoc":      {"ddoc push [-staged] dir | ddoc pull dir [name...]", "synchronises the design documents with a directory", runDDoc},
+		"replicate": {"replicate [options] source target", "replicates the source database to the target, names or urls", runReplicate},
+	}
+}
+
+// cli The state shared by the commands.
+type cli struct {
+	ctx    context.Context
+	client *couchcandy.CouchCandy
+	format string
+	stdin  io.Reader
+	stdout io.Writer
+	stderr io.Writer
+}
+
+func main() {
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	code := run(ctx, os.Args[1:], os.Stdin, os.Stdout, os.Stderr)
+	stop()
+	os.Exit(code)
+
+}
+
+// run runs the command line and returns the exit code : 2 for an invalid
+// usage, 1 when the command fails.
+func run(ctx context.Context, args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+
+	cli := &cli{ctx: ctx, stdin: stdin, stdout: stdout, stderr: stderr}
+	err := cli.run(args)
+	switch {
+	case err == nil:
+		return 0
+	case errors.Is(err, errUsage) || errors.Is(err, flag.ErrHelp):
+		return 2
+	default:
+		fmt.Fprintf(stderr, "couchcandy: %v\n", err)
+		return 1
+	}
+
+}
+
+func (c *cli) run(args []string) error {
+
+	config, err := couchcandy.LoadConfig("")
+	if err != nil {
+		return err
+	}
+
+	fs := flag.NewFlagSet("couchcandy", flag.ContinueOnError)
+	fs.SetOutput(c.stderr)
+	fs.StringVar(&c.format, "o", formatJSON, "output format, json or table")
+	config.BindFlags(fs)
+	fs.Usage = func() { c.usage(fs) }
+	if err = fs.Parse(args); err != nil {
+		return err
+	}
+
+	if fs.NArg() == 0 {
+		fs.Usage()
+		return errUsage
+	}
+	if c.format != formatJSON && c.format != formatTable {
+		fmt.Fprintf(c.stderr, "unknown output format %q\n", c.format)
+		return errUsage
+	}
+
+	cmd, ok := commands[fs.Arg(0)]
+	if !ok {
+		fmt.Fprintf(c.stderr, "unknown command %q\n", fs.Arg(0))
+		fs.Usage()
+		return errUsage
+	}
+
+	if c.client, err = config.NewClient(); err != nil {
+		return err
+	}
+	return cmd.run(c, fs.Args()[1:])
+
+}
+
+func (c *cli) usage(fs *flag.FlagSet) {
+
+	fmt.Fprintln(c.stderr, "usage: couchcandy [flags] command [arguments]")
+	fmt.Fprintln(c.stderr, "\ncommands:")
+
+	names := make([]string, 0, len(commands))
+	for name := range commands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		fmt.Fprintf(c.stderr, "  %s\n    \t%s\n", commands[name].usage, commands[name].summary)
+	}
+
+	fmt.Fprintln(c.stderr, "\nflags:")
+	fs.PrintDefaults()
+
+}
+
+// flagSet returns the flag set of a command, printing its usage on errors.
+func (c *cli) flagSet(name string) *flag.FlagSet {
+
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.SetOutput(c.stderr)
+	fs.Usage = func() {
+		fmt.Fprintf(c.stderr, "usage: couchcandy %s\n", commands[name].usage)
+		fs.PrintDefaults()
+	}
+	return fs
+
+}
+
+// parse parses the arguments of a command, which expects between min and
+// max positional arguments, max being -1 when there is no upper bound.
+func parse(fs *flag.FlagSet, args []string, min, max int) error {
+
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() < min || (max >= 0 && fs.NArg() > max) {
+		fs.Usage()
+		return errUsage
+	}
+	return nil
+
+}
+
+// db returns the database of the session, or of the name when not empty.
+func (c *cli) db(name string) (*couchcandy.DB, error) {
+
+	if name == "" {
+		name = c.client.Session.Database
+	}
+	if name == "" {
+		return nil, errors.New("no database, set COUCHDB_DATABASE or -database")
+	}
+	return c.client.DB(name), nil
+
+}
+
+// splitList splits a comma separated flag value, nil when empty.
+func splitList(value string) []string {
+
+	if value == "" {
+		return nil
+	}
+	return strings.Split(value, ",")
+
+}
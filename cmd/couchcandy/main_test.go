@@ -0,0 +1,205 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeCouch serves the canned responses keyed by method and escaped path,
+// and records the requests it receives.
+type fakeCouch struct {
+	responses map[string]string
+	requests  []string
+	bodies    []string
+}
+
+func (f *fakeCouch) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+
+	key := r.Method + " " + r.URL.EscapedPath()
+	body, _ := io.ReadAll(r.Body)
+	f.requests = append(f.requests, key+"?"+r.URL.RawQuery)
+	f.bodies = append(f.bodies, string(body))
+
+	response, ok := f.responses[key]
+	if !ok {
+		w.WriteHeader(http.StatusNotFound)
+		io.WriteString(w, `{"error":"not_found","reason":"missing"}`)
+		return
+	}
+	if r.Method == http.MethodHead {
+		w.Header().Set("ETag", response)
+		return
+	}
+	io.WriteString(w, response)
+
+}
+
+// runCouchCandy runs the command line against the fake server and returns
+// the exit code, stdout and stderr.
+func runCouchCandy(t *testing.T, couch *fakeCouch, stdin string, args ...string) (int, string, string) {
+
+	server := httptest.NewServer(couch)
+	t.Cleanup(server.Close)
+	t.Setenv("COUCHDB_URL", server.URL+"/cards")
+
+	stdout, stderr := &bytes.Buffer{}, &bytes.Buffer{}
+	code := run(context.Background(), args, strings.NewReader(stdin), stdout, stderr)
+	return code, stdout.String(), stderr.String()
+
+}
+
+func TestGet(t *testing.T) {
+
+	couch := &fakeCouch{responses: map[string]string{
+		"GET /cards/ace%20of%20spades/": `{"_id":"ace of spades","_rev":"1-abc","suit":"spades","rank":1}`,
+	}}
+
+	code, stdout, stderr := runCouchCandy(t, couch, "", "get", "ace of spades")
+	assert.Equal(t, 0, code, stderr)
+	assert.JSONEq(t, `{"_id":"ace of spades","_rev":"1-abc","suit":"spades","rank":1}`, stdout)
+
+	code, stdout, _ = runCouchCandy(t, couch, "", "-o", "table", "get", "ace of spades")
+	assert.Equal(t, 0, code)
+	assert.Equal(t, "FIELD  VALUE\n_id    ace of spades\n_rev   1-abc\nsuit   spades\nrank   1\n", stdout)
+
+	code, _, stderr = runCouchCandy(t, couch, "", "get", "joker")
+	assert.Equal(t, 1, code)
+	assert.Equal(t, "couchcandy: not_found: missing\n", stderr)
+
+}
+
+func TestPutAndDelete(t *testing.T) {
+
+	couch := &fakeCouch{responses: map[string]string{
+		"PUT /cards/card-1":    `{"ok":true,"id":"card-1","rev":"1-abc"}`,
+		"HEAD /cards/card-1":   `"1-abc"`,
+		"DELETE /cards/card-1": `{"ok":true,"id":"card-1","rev":"2-def"}`,
+	}}
+
+	code, stdout, stderr := runCouchCandy(t, couch, `{"suit": "hearts"}`, "-o", "table", "put", "card-1")
+	assert.Equal(t, 0, code, stderr)
+	assert.Equal(t, "ID      REV\ncard-1  1-abc\n", stdout)
+	assert.JSONEq(t, `{"suit":"hearts"}`, couch.bodies[0])
+
+	code, _, stderr = runCouchCandy(t, couch, "", "delete", "card-1")
+	assert.Equal(t, 0, code, stderr)
+	assert.Equal(t, "DELETE /cards/card-1?rev=1-abc", couch.requests[2])
+
+	code, _, stderr = runCouchCandy(t, couch, "{", "put", "card-1")
+	assert.Equal(t, 1, code)
+	assert.Contains(t, stderr, "not valid JSON")
+
+}
+
+func TestViewAndFind(t *testing.T) {
+
+	couch := &fakeCouch{responses: map[string]string{
+		"GET /cards/_design/cards/_view/by_suit": `{"total_rows":2,"offset":0,"rows":[
+			{"id":"card-1","key":"hearts","value":1},
+			{"id":"card-2","key":"spades","value":{"rank":2}}
+		]}`,
+		"POST /cards/_find": `{"docs":[{"_id":"card-1","suit":"hearts"},{"_id":"card-3"}]}`,
+	}}
+
+	code, stdout, stderr := runCouchCandy(t, couch, "", "-o", "table", "view", "-limit", "2", "-key", `"hearts"`, "cards", "by_suit")
+	assert.Equal(t, 0, code, stderr)
+	assert.Equal(t, "ID      KEY     VALUE\ncard-1  hearts  1\ncard-2  spades  {\"rank\":2}\n", stdout)
+	assert.Contains(t, couch.requests[0], "limit=2&key=%22hearts%22")
+
+	code, stdout, stderr = runCouchCandy(t, couch, `{"suit": {"$exists": true}}`, "-o", "table", "find", "-fields", "_id,suit", "-")
+	assert.Equal(t, 0, code, stderr)
+	assert.Equal(t, "_ID     SUIT\ncard-1  hearts\ncard-3  \n", stdout)
+	assert.JSONEq(t, `{"selector":{"suit":{"$exists":true}},"fields":["_id","suit"]}`, couch.bodies[1])
+
+}
+
+func TestChanges(t *testing.T) {
+
+	couch := &fakeCouch{responses: map[string]string{
+		"GET /cards/_changes": `{"results":[
+			{"seq":"1-g1AAAA","id":"card-1","changes":[{"rev":"1-abc"}]},
+			{"seq":"2-g1AAAA","id":"card-2","changes":[{"rev":"2-def"}],"deleted":true}
+		],"last_seq":"2-g1AAAA"}`,
+	}}
+
+	code, stdout, stderr := runCouchCandy(t, couch, "", "changes", "-since", "0")
+	assert.Equal(t, 0, code, stderr)
+	assert.Equal(t, `{"seq":"1-g1AAAA","id":"card-1","changes":[{"rev":"1-abc"}]}`+"\n"+
+		`{"seq":"2-g1AAAA","id":"card-2","changes":[{"rev":"2-def"}],"deleted":true}`+"\n", stdout)
+	assert.Equal(t, "GET /cards/_changes?feed=normal&since=0", couch.requests[0])
+
+	code, stdout, stderr = runCouchCandy(t, couch, "", "-o", "table", "changes", "-since", "0")
+	assert.Equal(t, 0, code, stderr)
+	assert.Equal(t, "SEQ       ID      REV    DELETED\n"+
+		"1-g1AAAA  card-1  1-abc  false\n"+
+		"2-g1AAAA  card-2  2-def  true\n", stdout)
+
+}
+
+func TestDesignDocPull(t *testing.T) {
+
+	couch := &fakeCouch{responses: map[string]string{
+		"GET /cards/_design/cards": `{"_id":"_design/cards","_rev":"3-abc","autoupdate":false,"views":{"lib":{"suits":"exports.all = []"},"by_suit":{"map":"function (doc) { emit(doc.suit) }"}}}`,
+	}}
+	dir := filepath.Join(t.TempDir(), "ddocs")
+
+	code, _, stderr := runCouchCandy(t, couch, "", "ddoc", "pull", dir, "cards")
+	assert.Equal(t, 0, code, stderr)
+
+	content, err := os.ReadFile(filepath.Join(dir, "cards.json"))
+	assert.Nil(t, err)
+	assert.JSONEq(t, `{"_id":"_design/cards","autoupdate":false,"views":{"lib":{"suits":"exports.all = []"},"by_suit":{"map":"function (doc) { emit(doc.suit) }"}}}`, string(content))
+
+}
+
+func TestImport(t *testing.T) {
+
+	couch := &fakeCouch{responses: map[string]string{
+		"POST /cards/_bulk_docs": `[{"ok":true,"id":"card:1","rev":"1-a"},{"id":"card:2","error":"conflict","reason":"Document update conflict."}]`,
+	}}
+	mapping := filepath.Join(t.TempDir(), "cards.yaml")
+	assert.Nil(t, os.WriteFile(mapping, []byte("fields:\n  - column: rank\n    type: integer\n"), 0600))
+
+	code, stdout, stderr := runCouchCandy(t, couch, "rank;suit\n1;hearts\n2;spades\n",
+		"-o", "table", "import", "-format", "csv", "-comma", ";", "-id", "card:{rank}", "-mapping", mapping)
+	assert.Equal(t, 1, code)
+	assert.Equal(t, "couchcandy: 1 of 2 rows were not imported\n", stderr)
+	assert.Equal(t, "ROWS  WRITTEN  FAILED\n2     1        1\n\nROW  ID      ERROR\n2    card:2  conflict: Document update conflict.\n", stdout)
+	assert.JSONEq(t, `{"docs":[{"_id":"card:1","rank":1},{"_id":"card:2","rank":2}]}`, couch.bodies[0])
+
+	code, _, stderr = runCouchCandy(t, couch, "", "import")
+	assert.Equal(t, 1, code)
+	assert.Contains(t, stderr, "set -format")
+
+}
+
+func TestUsage(t *testing.T) {
+
+	couch := &fakeCouch{}
+
+	code, _, stderr := runCouchCandy(t, couch, "", "shuffle")
+	assert.Equal(t, 2, code)
+	assert.Contains(t, stderr, `unknown command "shuffle"`)
+
+	code, _, _ = runCouchCandy(t, couch, "", "get")
+	assert.Equal(t, 2, code)
+
+	code, _, _ = runCouchCandy(t, couch, "", "-o", "yaml", "dbs")
+	assert.Equal(t, 2, code)
+
+	code, _, stderr = runCouchCandy(t, couch, "", "-database", "", "get", "card-1")
+	assert.Equal(t, 1, code)
+	assert.Contains(t, stderr, "no database")
+
+	assert.Empty(t, couch.requests)
+
+}
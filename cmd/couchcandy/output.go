@@ -0,0 +1,124 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/spacemojo/couchcandy"
+)
+
+// table The rows printed in the table format, under their header.
+type table struct {
+	header []string
+	rows   [][]string
+}
+
+// print prints v as indented JSON, or the table built by rows in the table
+// format.
+func (c *cli) print(v interface{}, rows func() table) error {
+
+	if c.format == formatTable {
+		return writeTable(c.stdout, rows())
+	}
+
+	body, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(c.stdout, "%s\n", body)
+	return err
+
+}
+
+func writeTable(w io.Writer, t table) error {
+
+	tw := newTabWriter(w)
+	if len(t.header) != 0 {
+		fmt.Fprintln(tw, strings.Join(t.header, "\t"))
+	}
+	for _, row := range t.rows {
+		fmt.Fprintln(tw, strings.Join(row, "\t"))
+	}
+	return tw.Flush()
+
+}
+
+// newTabWriter returns the writer aligning the columns of the tables.
+func newTabWriter(w io.Writer) *tabwriter.Writer {
+	return tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+}
+
+// cell returns the text of a JSON value in a table, strings being unquoted.
+func cell(value json.RawMessage) string {
+
+	var text string
+	if err := json.Unmarshal(value, &text); err == nil {
+		return text
+	}
+	return string(value)
+
+}
+
+// fieldsTable returns a row per field of the JSON object, in the order of
+// the object.
+func fieldsTable(object json.RawMessage) table {
+
+	t := table{header: []string{"FIELD", "VALUE"}}
+	decoder := json.NewDecoder(strings.NewReader(string(object)))
+	if token, err := decoder.Token(); err != nil || token != json.Delim('{') {
+		return t
+	}
+	for decoder.More() {
+		key, err := decoder.Token()
+		if err != nil {
+			return t
+		}
+		var value json.RawMessage
+		if err = decoder.Decode(&value); err != nil {
+			return t
+		}
+		t.rows = append(t.rows, []string{fmt.Sprint(key), cell(value)})
+	}
+	return t
+
+}
+
+// operationTable returns the table of the response to a write.
+func operationTable(response *couchcandy.OperationResponse) func() table {
+	return func() table {
+		return table{
+			header: []string{"ID", "REV"},
+			rows:   [][]string{{response.ID, response.REV}},
+		}
+	}
+}
+
+// checkOperation returns the error CouchDB answered a write with, if any.
+func checkOperation(response *couchcandy.OperationResponse, err error) (*couchcandy.OperationResponse, error) {
+
+	if err != nil {
+		return nil, err
+	}
+	if response.Error != "" {
+		return nil, fmt.Errorf("%s: %s", response.Error, response.Reason)
+	}
+	return response, nil
+
+}
+
+// checkDocument returns the error CouchDB answered a read with, if any.
+func checkDocument(document json.RawMessage) error {
+
+	failure := &struct {
+		Error  string `json:"error"`
+		Reason string `json:"reason"`
+	}{}
+	if err := json.Unmarshal(document, failure); err != nil || failure.Error == "" {
+		return nil
+	}
+	return fmt.Errorf("%s: %s", failure.Error, failure.Reason)
+
+}
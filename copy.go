@@ -0,0 +1,83 @@
+package couchcandy
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+const (
+	// MethodCopy is the CouchDB specific method copying a document
+	MethodCopy string = "COPY"
+	// HeaderDestination is the header holding the destination of a copy
+	HeaderDestination string = "Destination"
+	// HeaderETag is the header holding the revision of a document
+	HeaderETag string = "ETag"
+)
+
+// Copy Copies the document srcID to destID. When destID already exists, its
+// current revision must be passed as destRev, otherwise destRev is empty.
+func (db *DB) Copy(srcID, destID, destRev string) (*OperationResponse, error) {
+
+	destination := escapeDocID(destID)
+	if destRev != "" {
+		destination = fmt.Sprintf("%s?rev=%s", destination, destRev)
+	}
+
+	raw, err := db.newRequest(MethodCopy, createDocumentURL(db.session, srcID)).
+		Header(HeaderDestination, destination).
+		Raw()
+	if err != nil {
+		return nil, err
+	}
+
+	return toOperationResponse(raw.Body)
+
+}
+
+// Exists Tells if the document exists, without downloading it.
+func (db *DB) Exists(id string) (bool, error) {
+
+	res, err := db.head(id)
+	if err != nil {
+		return false, err
+	}
+
+	switch res.StatusCode {
+	case http.StatusOK, http.StatusNotModified:
+		return true, nil
+	case http.StatusNotFound:
+		return false, nil
+	}
+	return false, fmt.Errorf("unexpected status %d for %s", res.StatusCode, id)
+
+}
+
+// Revision Returns the current revision of the document, without downloading it.
+func (db *DB) Revision(id string) (string, error) {
+
+	res, err := db.head(id)
+	if err != nil {
+		return "", err
+	}
+
+	if res.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("unexpected status %d for %s", res.StatusCode, id)
+	}
+
+	return strings.Trim(res.Header.Get(HeaderETag), `"`), nil
+
+}
+
+func (db *DB) head(id string) (*http.Response, error) {
+
+	res, err := db.newRequest(http.MethodHead, createDocumentURL(db.session, id)).Do()
+	if err != nil {
+		return nil, err
+	}
+	if res.Body != nil {
+		res.Body.Close()
+	}
+	return res, nil
+
+}
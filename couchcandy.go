@@ -14,6 +14,14 @@
 //	 dbInfo, err :=  client.DatabaseInfo()
 //	 fmt.Printf("Database info : %s -> %d documents\n", dbInfo.DBName, dbInfo.DocCount)
 //
+// The client methods work on the database of the session. To work with other
+// databases, get a handle on them, and use the server API for the calls that
+// are not tied to a database :
+//
+//	archive := client.DB("archive")
+//	docs, err := archive.Documents(couchcandy.Options{Limit: 10})
+//	names, err := client.Server().AllDatabases()
+//
 // Note that it is highly recommended to never use a clear text password in the code, this snippet
 // is for introduction purposes only. A better practice would be to use some secret management tool
 // or environment variables.
@@ -22,13 +30,15 @@ package couchcandy
 import (
 	"encoding/json"
 	"fmt"
+	"net/http"
+	"strings"
 )
 
-// DatabaseInfo returns basic information about the database in session.
-func (c *CouchCandy) DatabaseInfo() (*DatabaseInfo, error) {
+// DatabaseInfo returns basic information about the database.
+func (db *DB) DatabaseInfo() (*DatabaseInfo, error) {
 
-	url := createDatabaseURL(c.Session)
-	page, err := readJSON(url, c.Get)
+	url := createDatabaseURL(db.session)
+	page, err := db.get(url)
 	if err != nil {
 		return nil, err
 	}
@@ -40,10 +50,10 @@ func (c *CouchCandy) DatabaseInfo() (*DatabaseInfo, error) {
 }
 
 // Document Returns the specified document.
-func (c *CouchCandy) Document(id string, v interface{}, options Options) error {
+func (db *DB) Document(id string, v interface{}, options Options) error {
 
-	url := createDocumentURLWithOptions(c.Session, id, options)
-	page, err := readJSON(url, c.Get)
+	url := createDocumentURLWithOptions(db.session, id, options)
+	page, err := db.get(url)
 	if err != nil {
 		return err
 	}
@@ -55,15 +65,15 @@ func (c *CouchCandy) Document(id string, v interface{}, options Options) error {
 
 // Add Adds a document in the database but the system will generate
 // an id. Look at PutDocumentWithID for setting an id for the document explicitly.
-func (c *CouchCandy) Add(document interface{}) (*OperationResponse, error) {
+func (db *DB) Add(document interface{}) (*OperationResponse, error) {
 
-	url := createDatabaseURL(c.Session)
+	url := createDatabaseURL(db.session)
 	bodyStr, marshallError := safeMarshall(document)
 	if marshallError != nil {
 		return nil, marshallError
 	}
 
-	page, err := readJSONWithBody(url, bodyStr, c.PostJSON)
+	page, err := db.postJSON(url, bodyStr)
 	if err != nil {
 		return nil, err
 	}
@@ -74,16 +84,16 @@ func (c *CouchCandy) Add(document interface{}) (*OperationResponse, error) {
 
 // Update Updates a document in the database. Note that _id and _rev
 // fields are required in the passed document.
-func (c *CouchCandy) Update(document interface{}) (*OperationResponse, error) {
+func (db *DB) Update(document interface{}) (*OperationResponse, error) {
 
 	bodyStr, marshallError := safeMarshall(document)
 	if marshallError != nil {
 		return nil, marshallError
 	}
 
-	url := createPutDocumentURL(c.Session, bodyStr)
+	url := createPutDocumentURL(db.session, bodyStr)
 
-	page, err := readJSONWithBody(url, bodyStr, c.PutJSON)
+	page, err := db.putJSON(url, bodyStr)
 	if err != nil {
 		return nil, err
 	}
@@ -93,16 +103,16 @@ func (c *CouchCandy) Update(document interface{}) (*OperationResponse, error) {
 }
 
 // AddWithID Inserts a document in the database with the specified id
-func (c *CouchCandy) AddWithID(id string, document interface{}) (*OperationResponse, error) {
+func (db *DB) AddWithID(id string, document interface{}) (*OperationResponse, error) {
 
-	url := fmt.Sprintf("%s/%s", createDatabaseURL(c.Session), id)
+	url := createDocumentURL(db.session, id)
 
 	bodyStr, marshallError := safeMarshall(document)
 	if marshallError != nil {
 		return nil, marshallError
 	}
 
-	page, err := readJSONWithBody(url, bodyStr, c.PutJSON)
+	page, err := db.putJSON(url, bodyStr)
 	if err != nil {
 		return nil, err
 	}
@@ -113,11 +123,11 @@ func (c *CouchCandy) AddWithID(id string, document interface{}) (*OperationRespo
 
 // AddAttachment adds the provided attachment to the specified
 // document and revision.
-func (c *CouchCandy) AddAttachment(id, rev, name, contentType string, file []byte) (*OperationResponse, error) {
+func (db *DB) AddAttachment(id, rev, name, contentType string, file []byte) (*OperationResponse, error) {
 
-	url := fmt.Sprintf("%s/%s/%s?rev=%s", createDatabaseURL(c.Session), id, name, rev)
+	url := fmt.Sprintf("%s?rev=%s", createAttachmentURL(db.session, id, name), rev)
 
-	page, err := readBytesWithBody(url, contentType, file, c.PutBytes)
+	page, err := db.putBytes(url, contentType, file)
 	if err != nil {
 		return nil, err
 	}
@@ -128,12 +138,12 @@ func (c *CouchCandy) AddAttachment(id, rev, name, contentType string, file []byt
 
 // DeleteAttachment delets the named attachment on the document corresponding
 // to the id-rev pair.
-func (c *CouchCandy) DeleteAttachment(id, rev, name string) (*OperationResponse, error) {
+func (db *DB) DeleteAttachment(id, rev, name string) (*OperationResponse, error) {
 
 	// DELETE /db/doc/attachmentname?rev=...
-	url := fmt.Sprintf("%s/%s/%s?rev=%s", createDatabaseURL(c.Session), id, name, rev)
+	url := fmt.Sprintf("%s?rev=%s", createAttachmentURL(db.session, id, name), rev)
 
-	page, err := readJSON(url, c.Delete)
+	page, err := db.delete(url)
 	if err != nil {
 		return nil, err
 	}
@@ -143,10 +153,10 @@ func (c *CouchCandy) DeleteAttachment(id, rev, name string) (*OperationResponse,
 }
 
 // Documents : Returns all documents in the database based on the passed parameters.
-func (c *CouchCandy) Documents(options Options) (*AllDocuments, error) {
+func (db *DB) Documents(options Options) (*AllDocuments, error) {
 
-	url := fmt.Sprintf("%s/_all_docs%s", createDatabaseURL(c.Session), toQueryString(options))
-	page, err := readJSON(url, c.Get)
+	url := fmt.Sprintf("%s/_all_docs%s", createDatabaseURL(db.session), toQueryString(options))
+	page, err := db.get(url)
 	if err != nil {
 		return nil, err
 	}
@@ -155,9 +165,11 @@ func (c *CouchCandy) Documents(options Options) (*AllDocuments, error) {
 
 }
 
-func (c *CouchCandy) DesignDocs() (*DesignDocs, error) {
+// DesignDocs Returns the design documents of the database, map/reduce
+// design documents and Mango indexes alike.
+func (db *DB) DesignDocs() ([]*DesignDocument, error) {
 
-	allDocuments, err := c.Documents(Options{
+	allDocuments, err := db.Documents(Options{
 		StartKey:    fmt.Sprintf("\"%s\"", "_design"),
 		EndKey:      fmt.Sprintf("\"%s\"", "_design0"),
 		IncludeDocs: true,
@@ -167,36 +179,20 @@ func (c *CouchCandy) DesignDocs() (*DesignDocs, error) {
 		return nil, err
 	}
 
-	designDocs := NewDesignDocs()
+	if allDocuments.Error != "" {
+		return nil, fmt.Errorf("%s: %s", allDocuments.Error, allDocuments.Reason)
+	}
+
+	designDocs := make([]*DesignDocument, 0, len(allDocuments.Rows))
 
 	for _, doc := range allDocuments.Rows {
 
-		partial := &partialDesignDoc{}
-
-		err = json.Unmarshal(doc.Doc, partial)
+		designDoc := &DesignDocument{}
+		err = json.Unmarshal(doc.Doc, designDoc)
 		if err != nil {
 			return nil, err
 		}
-
-		if partial.Language == "query" {
-
-			index := &IndexDesignDoc{}
-			err = json.Unmarshal(doc.Doc, index)
-			if err != nil {
-				return nil, err
-			}
-			designDocs.Index = append(designDocs.Index, index)
-
-		} else if partial.Language == "javascript" {
-
-			mapReduce := &MapReduceDesignDoc{}
-			err = json.Unmarshal(doc.Doc, mapReduce)
-			if err != nil {
-				return nil, err
-			}
-			designDocs.MapReduce = append(designDocs.MapReduce, mapReduce)
-
-		}
+		designDocs = append(designDocs, designDoc)
 
 	}
 
@@ -205,15 +201,15 @@ func (c *CouchCandy) DesignDocs() (*DesignDocs, error) {
 }
 
 // DocumentsByKeys Fetches all the documents corresponding to the passed keys array.
-func (c *CouchCandy) DocumentsByKeys(keys []string, options Options) (*AllDocuments, error) {
+func (db *DB) DocumentsByKeys(keys []string, options Options) (*AllDocuments, error) {
 
-	url := fmt.Sprintf("%s/_all_docs%s", createDatabaseURL(c.Session), toQueryString(options))
+	url := fmt.Sprintf("%s/_all_docs%s", createDatabaseURL(db.session), toQueryString(options))
 
 	body, _ := json.Marshal(&AllDocumentsKeys{
 		Keys: keys,
 	})
 
-	page, err := readJSONWithBody(url, string(body), c.PostJSON)
+	page, err := db.postJSON(url, string(body))
 	if err != nil {
 		return nil, err
 	}
@@ -222,40 +218,11 @@ func (c *CouchCandy) DocumentsByKeys(keys []string, options Options) (*AllDocume
 
 }
 
-// AddDatabase : Creates a database in CouchDB
-func (c *CouchCandy) AddDatabase(name string) (*OperationResponse, error) {
-
-	c.Session.Database = name
-	url := createDatabaseURL(c.Session)
-
-	page, err := readJSONWithBody(url, "", c.PutJSON)
-	if err != nil {
-		return nil, err
-	}
-
-	return toOperationResponse(page)
-
-}
-
-// DeleteDatabase : Deletes the passed database from the system.
-func (c *CouchCandy) DeleteDatabase(name string) (*OperationResponse, error) {
-
-	c.Session.Database = name
-	url := createDatabaseURL(c.Session)
-	page, err := readJSON(url, c.Delete)
-	if err != nil {
-		return nil, err
-	}
-
-	return toOperationResponse(page)
-
-}
-
 // DeleteDocument Deletes the passed document with revision from the database
-func (c *CouchCandy) DeleteDocument(id string, revision string) (*OperationResponse, error) {
+func (db *DB) DeleteDocument(id string, revision string) (*OperationResponse, error) {
 
-	url := fmt.Sprintf("%s?rev=%s", createDocumentURL(c.Session, id), revision)
-	page, err := readJSON(url, c.Delete)
+	url := fmt.Sprintf("%s?rev=%s", createDocumentURL(db.session, id), revision)
+	page, err := db.delete(url)
 	if err != nil {
 		return nil, err
 	}
@@ -264,26 +231,11 @@ func (c *CouchCandy) DeleteDocument(id string, revision string) (*OperationRespo
 
 }
 
-// AllDatabases : Returns all the database names in the system.
-func (c *CouchCandy) AllDatabases() ([]string, error) {
-
-	url := createAllDatabasesURL(c.Session)
-	page, err := readJSON(url, c.Get)
-	if err != nil {
-		return nil, err
-	}
-
-	var dbs []string
-	unmarshallError := json.Unmarshal(page, &dbs)
-	return dbs, unmarshallError
-
-}
-
 // ChangeNotifications : Return the current change notifications.
-func (c *CouchCandy) ChangeNotifications(options Options) (*Changes, error) {
+func (db *DB) ChangeNotifications(options Options) (*Changes, error) {
 
-	url := fmt.Sprintf("%s/_changes?style=%s", createDatabaseURL(c.Session), options.Style)
-	page, err := readJSON(url, c.Get)
+	url := fmt.Sprintf("%s/_changes?style=%s", createDatabaseURL(db.session), options.Style)
+	page, err := db.get(url)
 	if err != nil {
 		return nil, err
 	}
@@ -295,10 +247,10 @@ func (c *CouchCandy) ChangeNotifications(options Options) (*Changes, error) {
 }
 
 // View : Calls the passed view with provided options
-func (c *CouchCandy) View(ddoc, view string, options Options) (*ViewResponse, error) {
+func (db *DB) View(ddoc, view string, options Options) (*ViewResponse, error) {
 
-	url := fmt.Sprintf("%s/_design/%s/_view/%s%s", createDatabaseURL(c.Session), ddoc, view, toQueryString(options))
-	page, err := readJSON(url, c.Get)
+	url := fmt.Sprintf("%s%s", createDesignDocURL(db.session, ddoc, "_view", view), toQueryString(options))
+	page, err := db.get(url)
 	if err != nil {
 		return nil, err
 	}
@@ -306,15 +258,12 @@ func (c *CouchCandy) View(ddoc, view string, options Options) (*ViewResponse, er
 
 }
 
-// ViewWithList calls the passed view with list and options
-func (c *CouchCandy) ViewWithList(ddoc, list, view string, options Options) (*ViewResponse, error) {
+// ViewWithList calls the passed view with list and options. The output of a
+// list function can be anything, so it is returned as is along with its
+// content type.
+func (db *DB) ViewWithList(ddoc, list, view string, options Options) (*RawResponse, error) {
 
-	url := fmt.Sprintf("%s/_design/%s/_list/%s/%s/%s%s", createDatabaseURL(c.Session), ddoc, list, ddoc, view, toQueryString(options))
-	fmt.Printf("CouchCandy.CallView(%s)\n", url)
-	page, err := readJSON(url, c.Get)
-	if err != nil {
-		return nil, err
-	}
-	return toViewResponse(page)
+	url := fmt.Sprintf("%s%s", createDesignDocURL(db.session, ddoc, "_list", list, strings.TrimPrefix(ddoc, designPrefix), view), toQueryString(options))
+	return db.newRequest(http.MethodGet, url).Raw()
 
 }
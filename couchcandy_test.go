@@ -2,11 +2,11 @@ package couchcandy
 
 import (
 	"bytes"
-	"encoding/json"
 	"fmt"
 	"io/ioutil"
 	"net/http"
 	"strings"
+	"sync"
 	"testing"
 )
 
@@ -55,7 +55,7 @@ func TestDatabaseInfo(t *testing.T) {
 	couchcandy := NewCouchCandy(Session{
 		Host: "http://127.0.0.1", Port: 5984, Database: "lendr", Username: "test", Password: "gotest",
 	})
-	couchcandy.Get = func(string) (resp *http.Response, e error) {
+	mock(couchcandy).Get = func(string) (resp *http.Response, e error) {
 		response := &http.Response{
 			Body: ioutil.NopCloser(strings.NewReader(`{"db_name":"lendr","doc_count":102,"doc_del_count":0,"update_seq":103,"purge_seq":0,"compact_running":false,"disk_size":106600,"data_size":32561,"instance_start_time":"1535733632163685","disk_format_version":6,"committed_update_seq":103}`)),
 		}
@@ -74,46 +74,40 @@ func TestDatabaseInfo(t *testing.T) {
 
 func TestAllDesignDocuments(t *testing.T) {
 
-	allDocuments := &AllDocuments{}
-	err := json.Unmarshal([]byte(docs), allDocuments)
-	if err != nil {
-		t.Errorf("An error occurred whils unmarshaling docs: %v", err)
-	}
-
-	designDocs := NewDesignDocs()
-
-	for _, doc := range allDocuments.Rows {
-
-		partial := &partialDesignDoc{}
-
-		err = json.Unmarshal(doc.Doc, partial)
-		if err != nil {
-			t.Errorf("Error unmarshaling partial: %v", err)
+	couchcandy := NewCouchCandy(Session{
+		Host: "http://127.0.0.1", Port: 5984, Database: "lendr", Username: "test", Password: "gotest",
+	})
+	mock(couchcandy).Get = func(string) (*http.Response, error) {
+		response := &http.Response{
+			Body: ioutil.NopCloser(strings.NewReader(docs)),
 		}
-		t.Logf("Partial language: %s", partial.Language)
-		if partial.Language == "query" {
-
-			index := &IndexDesignDoc{}
-			err = json.Unmarshal(doc.Doc, index)
-			if err != nil {
-				t.Errorf("Error unmarshaling index: %v", err)
-			}
-			designDocs.Index = append(designDocs.Index, index)
-
-		} else if partial.Language == "javascript" {
-
-			mapReduce := &MapReduceDesignDoc{}
-			err = json.Unmarshal(doc.Doc, mapReduce)
-			if err != nil {
-				t.Errorf("Error unmarshaling mapReduce: %v", err)
-			}
-			designDocs.MapReduce = append(designDocs.MapReduce, mapReduce)
+		return response, nil
+	}
 
-		}
+	designDocs, err := couchcandy.DesignDocs()
+	if err != nil {
+		t.Errorf("An error occurred whilst fetching the design documents : %v", err)
+	}
+	if len(designDocs) != 10 {
+		t.Errorf("Expecting 10 design documents, got %d", len(designDocs))
+	}
 
+	index := designDocs[0]
+	if !index.IsIndex() {
+		t.Errorf("Expecting %s to be an index", index.ID)
+	}
+	fields := index.Views["createdon-sort-index"].Map.Index.Fields
+	if len(fields) != 1 || fields[0].Name != "createdon" || fields[0].Order != "asc" {
+		t.Errorf("Unexpected index fields : %v", fields)
 	}
 
-	t.Logf("Design docs : %d, %d", len(designDocs.MapReduce), len(designDocs.Index))
+	mapReduce := designDocs[1]
+	if mapReduce.IsIndex() || mapReduce.Views["_by_type"].Reduce != ReduceCount {
+		t.Errorf("Unexpected map/reduce design document : %v", mapReduce)
+	}
+	if !strings.HasPrefix(mapReduce.Views["_by_type"].Map.Function, "function (doc)") {
+		t.Errorf("Unexpected map function : %s", mapReduce.Views["_by_type"].Map.Function)
+	}
 
 }
 
@@ -122,7 +116,7 @@ func TestDatabaseInfoFailure(t *testing.T) {
 	couchcandy := NewCouchCandy(Session{
 		Host: "http://127.0.0.1", Port: 5984, Database: "lendr", Username: "test", Password: "gotest",
 	})
-	couchcandy.Get = func(string) (resp *http.Response, e error) {
+	mock(couchcandy).Get = func(string) (resp *http.Response, e error) {
 		return nil, fmt.Errorf("Expected failure")
 	}
 
@@ -138,7 +132,7 @@ func TestDocument(t *testing.T) {
 	couchcandy := NewCouchCandy(Session{
 		Host: "http://127.0.0.1", Port: 5984, Database: "lendr", Username: "test", Password: "gotest",
 	})
-	couchcandy.Get = func(string) (resp *http.Response, e error) {
+	mock(couchcandy).Get = func(string) (resp *http.Response, e error) {
 		response := &http.Response{
 			Body: ioutil.NopCloser(bytes.NewBufferString(`{"_id":"053cc05f2ee97a0c91d276c9e700194b","_rev":"3-b96f323b37f19c4d1affddf3db3da9c5","type":"com.lendrapp.beans.UserProfile","shortProfile":{"id":null,"firstname":"Patrick","lastname":"Fitzgerald","email":"brun@email.com","organizationId":"053cc05f2ee97a0c91d276c9e700268f","password":"ee0c9435d5e2a07ceaa8abc829990dd3bdd15b7d6d3b0eaac100984da0841530"},"accountType":"PERSONAL","contacts":[],
 				"_revisions":{"start":3,"ids":["b96f323b37f19c4d1affddf3db3da9c5","bdeff0741cc1425e5f5b3829a7a9af2f","c76ae1eb708d6eb68974600995b98b70"]}}`)),
@@ -162,7 +156,7 @@ func TestDocumentFailure(t *testing.T) {
 	couchcandy := NewCouchCandy(Session{
 		Host: "http://127.0.0.1", Port: 5984, Database: "cards", Username: "admin", Password: "gotest",
 	})
-	couchcandy.Get = func(string) (resp *http.Response, e error) {
+	mock(couchcandy).Get = func(string) (resp *http.Response, e error) {
 		return nil, fmt.Errorf("Deliberate error from TestGetDocumentFailure()")
 	}
 
@@ -182,7 +176,7 @@ func TestDeleteSuccess(t *testing.T) {
 	couchcandy := NewCouchCandy(Session{
 		Host: "http://127.0.0.1", Port: 5984, Database: "lendr", Username: "test", Password: "gotest",
 	})
-	couchcandy.Delete = func(string) (resp *http.Response, e error) {
+	mock(couchcandy).Delete = func(string) (resp *http.Response, e error) {
 		response := &http.Response{
 			Body: ioutil.NopCloser(bytes.NewBufferString(`{"_id":"053cc05f2ee97a0c91d276c9e700194b","_rev":"3-b96f323b37f19c4d1affddf3db3da9c5","ok":true}`)),
 		}
@@ -204,7 +198,7 @@ func TestDeleteFailure(t *testing.T) {
 	couchcandy := NewCouchCandy(Session{
 		Host: "http://127.0.0.1", Port: 5984, Database: "lendr", Username: "test", Password: "gotest",
 	})
-	couchcandy.Delete = func(string) (resp *http.Response, e error) {
+	mock(couchcandy).Delete = func(string) (resp *http.Response, e error) {
 		return nil, fmt.Errorf("an error occurred when deleting the document")
 	}
 
@@ -221,7 +215,7 @@ func TestAllDatabases(t *testing.T) {
 		Host: "http://127.0.0.1", Port: 5984, Database: "lendr", Username: "test", Password: "gotest",
 	}
 	couchcandy := NewCouchCandy(session)
-	couchcandy.Get = func(string) (resp *http.Response, e error) {
+	mock(couchcandy).Get = func(string) (resp *http.Response, e error) {
 		response := &http.Response{
 			Body: ioutil.NopCloser(bytes.NewBufferString(`["_replicator","_users","baseball","baseball20170228","elements","lendr","social"]`)),
 		}
@@ -242,7 +236,7 @@ func TestAllDatabasesFailure(t *testing.T) {
 		Host: "http://127.0.0.1", Port: 5984, Database: "lendr", Username: "test", Password: "gotest",
 	}
 	couchcandy := NewCouchCandy(session)
-	couchcandy.Get = func(string) (resp *http.Response, e error) {
+	mock(couchcandy).Get = func(string) (resp *http.Response, e error) {
 		return nil, fmt.Errorf("Deliberate error from TestAllDatabasesFailure()")
 	}
 
@@ -259,7 +253,7 @@ func TestChangeNotificatios(t *testing.T) {
 		Host: "http://127.0.0.1", Port: 5984, Database: "lendr", Username: "test", Password: "gotest",
 	}
 	couchcandy := NewCouchCandy(session)
-	couchcandy.Get = func(string) (resp *http.Response, e error) {
+	mock(couchcandy).Get = func(string) (resp *http.Response, e error) {
 		response := &http.Response{
 			Body: ioutil.NopCloser(bytes.NewBufferString(`{"results":[
 				{"seq":19215,"id":"actama99","changes":[{"rev":"1-e860e99218e7c618f3510c48987d6ff0"}]},
@@ -289,7 +283,7 @@ func TestChangeNotificatiosFailure(t *testing.T) {
 		Host: "http://127.0.0.1", Port: 5984, Database: "lendr", Username: "test", Password: "gotest",
 	}
 	couchcandy := NewCouchCandy(session)
-	couchcandy.Get = func(string) (resp *http.Response, e error) {
+	mock(couchcandy).Get = func(string) (resp *http.Response, e error) {
 		return nil, fmt.Errorf("Deliberate error in TestChangeNotificatiosFailure")
 	}
 
@@ -309,7 +303,7 @@ func TestAddWithID(t *testing.T) {
 	}
 
 	couchcandy := NewCouchCandy(session)
-	couchcandy.PutJSON = func(string, string) (*http.Response, error) {
+	mock(couchcandy).PutJSON = func(string, string) (*http.Response, error) {
 		response := &http.Response{
 			Body: ioutil.NopCloser(bytes.NewBufferString(`{"id":"1029384756", "rev":"1-b2b5fcc9f6ca0efcd401b9bc40f539cc", "ok": true}`)),
 		}
@@ -330,7 +324,7 @@ func TestAddWithIDFailure(t *testing.T) {
 	}
 
 	couchcandy := NewCouchCandy(session)
-	couchcandy.PutJSON = func(string, string) (*http.Response, error) {
+	mock(couchcandy).PutJSON = func(string, string) (*http.Response, error) {
 		return nil, fmt.Errorf("Deliberate error thrown in TestAddWithIDFailure")
 	}
 
@@ -361,7 +355,7 @@ func TestUpdate(t *testing.T) {
 	}
 
 	couchcandy := NewCouchCandy(session)
-	couchcandy.PutJSON = func(string, string) (*http.Response, error) {
+	mock(couchcandy).PutJSON = func(string, string) (*http.Response, error) {
 		response := &http.Response{
 			Body: ioutil.NopCloser(bytes.NewBufferString(`{"id":"1029384756", "rev":"1-b2b5fcc9f6ca0efcd401b9bc40f539cc", "ok": true}`)),
 		}
@@ -380,7 +374,7 @@ func TestUpdateFailure(t *testing.T) {
 	couchcandy := NewCouchCandy(Session{
 		Host: "http://127.0.0.1", Port: 5984, Database: "lendr", Username: "test", Password: "gotest",
 	})
-	couchcandy.PutJSON = func(string, string) (*http.Response, error) {
+	mock(couchcandy).PutJSON = func(string, string) (*http.Response, error) {
 		return nil, fmt.Errorf("Deliberate error from TestUpdateFailure test")
 	}
 
@@ -409,7 +403,7 @@ func TestAdd(t *testing.T) {
 	couchcandy := NewCouchCandy(Session{
 		Host: "http://127.0.0.1", Port: 5984, Database: "lendr", Username: "test", Password: "gotest",
 	})
-	couchcandy.PostJSON = func(string, string) (*http.Response, error) {
+	mock(couchcandy).PostJSON = func(string, string) (*http.Response, error) {
 		response := &http.Response{
 			Body: ioutil.NopCloser(bytes.NewBufferString(`{"id":"1029384756", "rev":"1-b2b5fcc9f6ca0efcd401b9bc40f539cc", "ok": true}`)),
 		}
@@ -428,7 +422,7 @@ func TestAddFailure(t *testing.T) {
 	couchcandy := NewCouchCandy(Session{
 		Host: "http://127.0.0.1", Port: 5984, Database: "lendr", Username: "test", Password: "gotest",
 	})
-	couchcandy.PostJSON = func(string, string) (*http.Response, error) {
+	mock(couchcandy).PostJSON = func(string, string) (*http.Response, error) {
 		return nil, fmt.Errorf("Deliberate error in TestAddFailure")
 	}
 
@@ -458,7 +452,7 @@ func TestAddDatabase(t *testing.T) {
 		Host: "http://127.0.0.1", Port: 5984, Database: "lendr", Username: "test", Password: "gotest",
 	}
 	couchcandy := NewCouchCandy(session)
-	couchcandy.PutJSON = func(string, string) (resp *http.Response, e error) {
+	mock(couchcandy).PutJSON = func(string, string) (resp *http.Response, e error) {
 		response := &http.Response{
 			Body: ioutil.NopCloser(bytes.NewBufferString(`{"ok": true}`)),
 		}
@@ -478,7 +472,7 @@ func TestAddDatabaseFailure(t *testing.T) {
 		Host: "http://127.0.0.1", Port: 5984, Database: "lendr", Username: "test", Password: "gotest",
 	}
 	couchcandy := NewCouchCandy(session)
-	couchcandy.PutJSON = func(string, string) (resp *http.Response, e error) {
+	mock(couchcandy).PutJSON = func(string, string) (resp *http.Response, e error) {
 		return nil, fmt.Errorf("Deliberate error from TestAddDatabaseFailure()")
 	}
 
@@ -495,7 +489,7 @@ func TestDeleteDatabase(t *testing.T) {
 		Host: "http://127.0.0.1", Port: 5984, Database: "lendr", Username: "test", Password: "gotest",
 	}
 	couchcandy := NewCouchCandy(session)
-	couchcandy.Delete = func(string) (resp *http.Response, e error) {
+	mock(couchcandy).Delete = func(string) (resp *http.Response, e error) {
 		response := &http.Response{
 			Body: ioutil.NopCloser(bytes.NewBufferString(`{"ok": true}`)),
 		}
@@ -515,7 +509,7 @@ func TestDeleteDatabaseFailure(t *testing.T) {
 		Host: "http://127.0.0.1", Port: 5984, Database: "lendr", Username: "test", Password: "gotest",
 	}
 	couchcandy := NewCouchCandy(session)
-	couchcandy.Delete = func(string) (resp *http.Response, e error) {
+	mock(couchcandy).Delete = func(string) (resp *http.Response, e error) {
 		return nil, fmt.Errorf("Deliberate error from TestDeleteDatabaseFailure()")
 	}
 
@@ -531,7 +525,7 @@ func TestDocumentsByKeys(t *testing.T) {
 	couchcandy := NewCouchCandy(Session{
 		Host: "http://127.0.0.1", Port: 5984, Database: "userapi", Username: "test", Password: "pwd",
 	})
-	couchcandy.PostJSON = func(string, string) (*http.Response, error) {
+	mock(couchcandy).PostJSON = func(string, string) (*http.Response, error) {
 		response := &http.Response{
 			Body: ioutil.NopCloser(bytes.NewBufferString(`{
 			"total_rows": 5,
@@ -588,7 +582,7 @@ func TestDocumentsByKeysFailure(t *testing.T) {
 	couchcandy := NewCouchCandy(Session{
 		Host: "http://127.0.0.1", Port: 5984, Database: "userapi", Username: "test", Password: "pwd",
 	})
-	couchcandy.PostJSON = func(string, string) (*http.Response, error) {
+	mock(couchcandy).PostJSON = func(string, string) (*http.Response, error) {
 		return nil, fmt.Errorf("An error occurred when fetching documents by keys")
 	}
 
@@ -605,7 +599,7 @@ func TestAllDocuments(t *testing.T) {
 		Host: "http://127.0.0.1", Port: 5984, Database: "lendr", Username: "test", Password: "gotest",
 	}
 	couchcandy := NewCouchCandy(session)
-	couchcandy.Get = func(string) (*http.Response, error) {
+	mock(couchcandy).Get = func(string) (*http.Response, error) {
 		response := &http.Response{
 			Body: ioutil.NopCloser(bytes.NewBufferString(`{"total_rows":20682,"offset":0,"rows":[
 			{"id":"ALB01","key":"ALB01","value":{"rev":"1-66a2e993bd32c834f4c2bb655b520c42"}},
@@ -638,7 +632,7 @@ func TestAllDocumentsFailure(t *testing.T) {
 	couchcandy := NewCouchCandy(Session{
 		Host: "http://127.0.0.1", Port: 5984, Database: "lendr", Username: "test", Password: "gotest",
 	})
-	couchcandy.Get = func(string) (resp *http.Response, e error) {
+	mock(couchcandy).Get = func(string) (resp *http.Response, e error) {
 		return nil, fmt.Errorf("Deliberate error from the TestAllDocumentsFailure test")
 	}
 
@@ -662,7 +656,7 @@ func TestAddAttachmentToDocument(t *testing.T) {
 		Host: "http://127.0.0.1", Port: 5984, Database: "lendr", Username: "test", Password: "gotest",
 	})
 
-	couchcandy.PutBytes = func(string, string, []byte) (*http.Response, error) {
+	mock(couchcandy).PutBytes = func(string, string, []byte) (*http.Response, error) {
 		response := &http.Response{
 			Body: ioutil.NopCloser(bytes.NewBufferString(`{
 				"ok":true,
@@ -686,7 +680,7 @@ func TestDeleteAttachmentFromDocument(t *testing.T) {
 		Host: "http://127.0.0.1", Port: 5984, Database: "lendr", Username: "test", Password: "gotest",
 	})
 
-	couchcandy.Delete = func(string) (*http.Response, error) {
+	mock(couchcandy).Delete = func(string) (*http.Response, error) {
 		response := &http.Response{
 			Body: ioutil.NopCloser(bytes.NewBufferString(`{"ok":true,"id":"6c53db4ead49660dad9e43b0a9002108","rev":"3-e9f18d929badf964695f48add785f046"}`)),
 		}
@@ -705,7 +699,7 @@ func TestCallMapFunction(t *testing.T) {
 	couchcandy := NewCouchCandy(Session{
 		Host: "http://127.0.0.1", Port: 5984, Database: "lendr", Username: "test", Password: "gotest",
 	})
-	couchcandy.Get = func(string) (*http.Response, error) {
+	mock(couchcandy).Get = func(string) (*http.Response, error) {
 		response := &http.Response{
 			Body: ioutil.NopCloser(bytes.NewBufferString(`{"total_rows":52,"offset":39,"rows":[
 			{"id":"899b677618b95aad18fae36b6c000310","key":"spades","value":{"_id":"899b677618b95aad18fae36b6c000310","_rev":"1-628016c8a8a997b20359cd5eec8cfd1b","id":"1-spades","suit":"spades","numericValue":1,"name":"Ace","color":"black"}},
@@ -735,7 +729,7 @@ func TestCallMapFunctionFailure(t *testing.T) {
 	couchcandy := NewCouchCandy(Session{
 		Host: "http://127.0.0.1", Port: 5984, Database: "lendr", Username: "test", Password: "gotest",
 	})
-	couchcandy.Get = func(string) (*http.Response, error) {
+	mock(couchcandy).Get = func(string) (*http.Response, error) {
 		return nil, fmt.Errorf("an error occurred whilst calling the map function")
 	}
 
@@ -773,7 +767,8 @@ func (m *MockRunningHTTPClient) Do(request *http.Request) (*http.Response, error
 
 func TestDefaultHandlerSuccess(t *testing.T) {
 
-	response, _ := defaultMethod(http.MethodGet, "http://127.0.0.1:5984/dbase", &MockRunningHTTPClient{})
+	couchcandy := &CouchCandy{Transport: &MockRunningHTTPClient{}}
+	response, _ := couchcandy.NewRequest(http.MethodGet, "http://127.0.0.1:5984/dbase").Do()
 	if response == nil {
 		t.Fail()
 	}
@@ -782,7 +777,8 @@ func TestDefaultHandlerSuccess(t *testing.T) {
 
 func TestDefaultHandlerWithBodySuccess(t *testing.T) {
 
-	response, _ := defaultJSONWithBody(http.MethodPost, "http://127.0.0.1:5984/dbase", "This is the body for the post.", &MockRunningHTTPClient{})
+	couchcandy := &CouchCandy{Transport: &MockRunningHTTPClient{}}
+	response, _ := couchcandy.NewRequest(http.MethodPost, "http://127.0.0.1:5984/dbase").JSON("This is the body for the post.").Do()
 	if response == nil {
 		t.Fail()
 	}
@@ -791,7 +787,8 @@ func TestDefaultHandlerWithBodySuccess(t *testing.T) {
 
 func TestDefaultHandlerDoFail(t *testing.T) {
 
-	_, err := defaultMethod(http.MethodGet, "http://127.0.0.1:5984/dbase", &MockFailingHTTPClient{})
+	couchcandy := &CouchCandy{Transport: &MockFailingHTTPClient{}}
+	_, err := couchcandy.NewRequest(http.MethodGet, "http://127.0.0.1:5984/dbase").Do()
 	if err == nil {
 		t.Fail()
 	}
@@ -800,7 +797,8 @@ func TestDefaultHandlerDoFail(t *testing.T) {
 
 func TestDefaultHandlerDoRequestFail(t *testing.T) {
 
-	_, err := defaultMethod("\n", "http://127.0.0.1:5984/dbase", &MockFailingHTTPClient{})
+	couchcandy := &CouchCandy{Transport: &MockFailingHTTPClient{}}
+	_, err := couchcandy.NewRequest("\n", "http://127.0.0.1:5984/dbase").Do()
 	if err == nil {
 		t.Fail()
 	}
@@ -809,7 +807,8 @@ func TestDefaultHandlerDoRequestFail(t *testing.T) {
 
 func TestDefaultHandlerWithBodyDoFail(t *testing.T) {
 
-	_, err := defaultJSONWithBody(http.MethodPost, "http://127.0.0.1:5984/dbase", "Body", &MockFailingHTTPClient{})
+	couchcandy := &CouchCandy{Transport: &MockFailingHTTPClient{}}
+	_, err := couchcandy.NewRequest(http.MethodPost, "http://127.0.0.1:5984/dbase").JSON("Body").Do()
 	if err == nil {
 		t.Fail()
 	}
@@ -818,13 +817,144 @@ func TestDefaultHandlerWithBodyDoFail(t *testing.T) {
 
 func TestDefaultHandlerWithBodyDoRequestFail(t *testing.T) {
 
-	_, err := defaultJSONWithBody(http.MethodPost, "http://127.0.0.1:5984/dbase", "Body", &MockFailingHTTPClient{})
+	couchcandy := &CouchCandy{Transport: &MockFailingHTTPClient{}}
+	_, err := couchcandy.NewRequest("\n", "http://127.0.0.1:5984/dbase").JSON("Body").Do()
 	if err == nil {
 		t.Fail()
 	}
 
 }
 
+// mockTransport dispatches the requests of a client to the handlers set by a
+// test, following the method and the content type of the request.
+type mockTransport struct {
+	Get      func(string) (*http.Response, error)
+	PostJSON func(string, string) (*http.Response, error)
+	PutJSON  func(string, string) (*http.Response, error)
+	PutBytes func(string, string, []byte) (*http.Response, error)
+	Delete   func(string) (*http.Response, error)
+	Other    func(method, url string, header http.Header, body []byte) (*http.Response, error)
+}
+
+// mock installs a mockTransport on the client, or returns the installed one.
+func mock(c *CouchCandy) *mockTransport {
+	if m, ok := c.Transport.(*mockTransport); ok {
+		return m
+	}
+	m := &mockTransport{}
+	c.Transport = m
+	return m
+}
+
+func (m *mockTransport) Do(request *http.Request) (*http.Response, error) {
+
+	url := request.URL.String()
+	var body []byte
+	if request.Body != nil {
+		body, _ = ioutil.ReadAll(request.Body)
+	}
+	contentType := request.Header.Get(HeaderContentType)
+
+	switch {
+	case request.Method == http.MethodGet && m.Get != nil:
+		return m.Get(url)
+	case request.Method == http.MethodDelete && m.Delete != nil:
+		return m.Delete(url)
+	case request.Method == http.MethodPost && m.PostJSON != nil:
+		return m.PostJSON(url, string(body))
+	case request.Method == http.MethodPut && contentType == JSONContentType && m.PutJSON != nil:
+		return m.PutJSON(url, string(body))
+	case request.Method == http.MethodPut && contentType != JSONContentType && m.PutBytes != nil:
+		return m.PutBytes(url, contentType, body)
+	case m.Other != nil:
+		return m.Other(request.Method, url, request.Header, body)
+	}
+	return nil, fmt.Errorf("unexpected %s %s", request.Method, url)
+
+}
+
+// newTestClient returns a client on the lendr database, for the tests
+// installing a fake transport.
+func newTestClient() *CouchCandy {
+	return NewCouchCandy(Session{
+		Host: "http://127.0.0.1", Port: 5984, Database: "lendr", Username: "test", Password: "gotest",
+	})
+}
+
+// routeHandler answers a request, whose body was already read.
+type routeHandler func(req *http.Request, body []byte) (*http.Response, error)
+
+// router is a fake CouchDB answering the requests of a client with the
+// handler set for their method and path, or for their path alone, and with
+// a not_found error otherwise. It records every request as "METHOD path?query".
+type router struct {
+	lock     sync.Mutex
+	routes   map[string]routeHandler
+	requests []string
+}
+
+// route installs a router on the client, or returns the installed one.
+func route(c *CouchCandy) *router {
+	if r, ok := c.Transport.(*router); ok {
+		return r
+	}
+	r := &router{routes: make(map[string]routeHandler)}
+	c.Transport = r
+	return r
+}
+
+// Handle sets the handler of the requests with the method, any method when
+// empty, on the path.
+func (r *router) Handle(method, path string, handler routeHandler) *router {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	r.routes[method+" "+path] = handler
+	return r
+}
+
+// Reply answers the requests with the method on the path with the status and body.
+func (r *router) Reply(method, path string, status int, body string) *router {
+	return r.Handle(method, path, func(*http.Request, []byte) (*http.Response, error) {
+		return statusResponse(status, body), nil
+	})
+}
+
+// Requests returns the requests received so far.
+func (r *router) Requests() []string {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	return append([]string(nil), r.requests...)
+}
+
+// Reset forgets the requests received so far.
+func (r *router) Reset() {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	r.requests = r.requests[:0]
+}
+
+func (r *router) Do(req *http.Request) (*http.Response, error) {
+
+	var body []byte
+	if req.Body != nil {
+		body, _ = ioutil.ReadAll(req.Body)
+	}
+
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	r.requests = append(r.requests, req.Method+" "+req.URL.Path+"?"+req.URL.RawQuery)
+
+	handler, ok := r.routes[req.Method+" "+req.URL.Path]
+	if !ok {
+		handler, ok = r.routes[" "+req.URL.Path]
+	}
+	if !ok {
+		return statusResponse(http.StatusNotFound, `{"error":"not_found","reason":"missing"}`), nil
+	}
+	return handler(req, body)
+
+}
+
 func TestCreateUpdateURL(t *testing.T) {
 
 	url := createPutDocumentURL(Session{}, "{badBodyFormat}")
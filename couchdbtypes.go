@@ -2,8 +2,10 @@ package couchcandy
 
 import (
 	"encoding/json"
+	"log/slog"
 	"net/http"
 	"os"
+	"time"
 )
 
 const (
@@ -17,6 +19,9 @@ const (
 	JSONContentType string = "application/json"
 )
 
+// localPrefix is the id prefix of non-replicated documents
+const localPrefix = "_local/"
+
 // CandyHTTPClient Interface that describes a client that executes an
 // http request and produces an http response, and error if any.
 type CandyHTTPClient interface {
@@ -52,7 +57,8 @@ type Revision struct {
 	IDS   []string `json:"ids,omitempty"`
 }
 
-// NewDBSession returns an inited session with the available environment variables
+// NewDBSession returns an inited session with the available environment variables.
+// See LoadConfig for the COUCHDB_* variables, files and flags.
 func NewDBSession() Session {
 	return Session{
 		Host:     os.Getenv("dbhost"),
@@ -69,13 +75,17 @@ func NewClient() *CouchCandy {
 }
 
 // CouchCandy Struct that provides all CouchDB's API has to offer.
+// All requests are sent to the Transport through the Middlewares, which
+// tests can replace with a fake transport. When Logger is set, every
+// request is logged as tuned by LogOptions, and Instrumentation is called
+// around every request when set.
 type CouchCandy struct {
-	Session  Session
-	Get      func(string) (*http.Response, error)
-	PostJSON func(string, string) (*http.Response, error)
-	PutJSON  func(string, string) (*http.Response, error)
-	PutBytes func(string, string, []byte) (*http.Response, error)
-	Delete   func(string) (*http.Response, error)
+	Session         Session
+	Transport       CandyHTTPClient
+	Middlewares     []Middleware
+	Logger          *slog.Logger
+	LogOptions      LogOptions
+	Instrumentation Instrumentation
 }
 
 // Changes The struct returned by the call to get change notifications.
@@ -122,12 +132,8 @@ type Options struct {
 // NewCouchCandy Returns a new CouchCandy struct initialised with the provided values.
 func NewCouchCandy(session Session) *CouchCandy {
 	return &CouchCandy{
-		Session:  session,
-		Get:      defaultGet,
-		PostJSON: defaultPostJSON,
-		PutJSON:  defaultPutJSON,
-		PutBytes: defaultPutBytes,
-		Delete:   defaultDelete,
+		Session:   session,
+		Transport: &http.Client{},
 	}
 }
 
@@ -155,74 +161,36 @@ type OperationResponse struct {
 	Reason string `json:"reason,omitempty"`
 }
 
-// Session holds the connection data for a couchcandy session.
-type Session struct {
-	Host     string
-	Port     int
-	Database string
-	Username string
-	Password string
-}
-
-// DesignDocs
-type DesignDocs struct {
-	MapReduce []*MapReduceDesignDoc `json:"mapreduce"`
-	Index     []*IndexDesignDoc     `json:"index"`
-}
-
-func NewDesignDocs() *DesignDocs {
-	d := &DesignDocs{}
-	d.MapReduce = make([]*MapReduceDesignDoc, 0)
-	d.Index = make([]*IndexDesignDoc, 0)
-	return d
-}
-
-type partialDesignDoc struct {
-	Language string `json:"language,omitempty"`
-}
-
-type MapReduceDesignDoc struct {
-	ID       string          `json:"_id,omitempty"`
-	REV      string          `json:"_rev,omitempty"`
-	Language string          `json:"language,omitempty"`
-	Views    map[string]View `json:"views,omitempty"`
-}
-
-// Map / Reduce View when the language is "javascript"
-type View struct {
-	Map    string `json:"map,omitempty"`
-	Reduce string `json:"reduce,omitempty"`
-}
-
-type IndexDesignDoc struct {
-	ID       string               `json:"_id,omitempty"`
-	REV      string               `json:"_rev,omitempty"`
-	Language string               `json:"language,omitempty"`
-	Views    map[string]IndexView `json:"views,omitempty"`
-}
-
-// IndexView when the language is "query"
-type IndexView struct {
-	Map     IndexMap     `json:"map"`
-	Reduce  string       `json:"reduce"`
-	Options IndexOptions `json:"options"`
-}
-
-type IndexMap struct {
-	Fields                Fields                 `json:"fields"`
-	PartialFilterSelector map[string]interface{} `json:"partial_filter_selector"`
-}
-
-type Fields struct {
-	CreatedOn string `json:"createdon"`
+// CommitResponse The response to an _ensure_full_commit request.
+type CommitResponse struct {
+	OK                bool   `json:"ok,omitempty"`
+	InstanceStartTime string `json:"instance_start_time,omitempty"`
+	Error             string `json:"error,omitempty"`
+	Reason            string `json:"reason,omitempty"`
 }
 
-type IndexOptions struct {
-	Def Def `json:"def"`
+// PurgeResponse The response to a _purge request. Purged holds the revisions
+// that were effectively purged, keyed by document id.
+type PurgeResponse struct {
+	PurgeSeq json.RawMessage     `json:"purge_seq,omitempty"`
+	Purged   map[string][]string `json:"purged,omitempty"`
+	Error    string              `json:"error,omitempty"`
+	Reason   string              `json:"reason,omitempty"`
 }
 
-type Def struct {
-	Fields []string `json:"fields"`
+// Session holds the connection data for a couchcandy session.
+// Host : scheme and host name, like "https://couch.example.com" or "http://[::1]"
+// PathPrefix : path CouchDB is served under, behind a reverse proxy, like "couch"
+// Timeout : bound of every request, feeds excepted, 0 for none
+// See ParseDSN to build a session from a connection string.
+type Session struct {
+	Host       string
+	Port       int
+	PathPrefix string
+	Database   string
+	Username   string
+	Password   string
+	Timeout    time.Duration
 }
 
 // ViewResponse represents the response sent when a view is called
@@ -271,3 +239,100 @@ type Value struct {
 type AllDocumentsKeys struct {
 	Keys []string `json:"keys,omitempty"`
 }
+
+// ServerInfo The welcome message returned by the root of the server.
+type ServerInfo struct {
+	CouchDB  string   `json:"couchdb,omitempty"`
+	Version  string   `json:"version,omitempty"`
+	GitSHA   string   `json:"git_sha,omitempty"`
+	UUID     string   `json:"uuid,omitempty"`
+	Features []string `json:"features,omitempty"`
+	Vendor   Vendor   `json:"vendor,omitempty"`
+}
+
+// Vendor The vendor of the CouchDB server.
+type Vendor struct {
+	Name    string `json:"name,omitempty"`
+	Version string `json:"version,omitempty"`
+}
+
+// UpStatus The status returned by the _up health check.
+type UpStatus struct {
+	Status string          `json:"status,omitempty"`
+	Seeds  json.RawMessage `json:"seeds,omitempty"`
+}
+
+// IsUp Tells if the node reported itself as able to serve requests.
+func (u *UpStatus) IsUp() bool {
+	return u.Status == "ok"
+}
+
+const (
+	// TaskDatabaseCompaction is the type of database compaction tasks
+	TaskDatabaseCompaction string = "database_compaction"
+	// TaskViewCompaction is the type of view compaction tasks
+	TaskViewCompaction string = "view_compaction"
+	// TaskIndexer is the type of view indexing tasks
+	TaskIndexer string = "indexer"
+	// TaskReplication is the type of replication tasks
+	TaskReplication string = "replication"
+)
+
+// ActiveTask A task running on the server. The Type field tells which kind of
+// task it is and thus which of the fields are set : compaction and indexer
+// tasks report their progress with ChangesDone and TotalChanges, replication
+// tasks with the documents read and written and the sequences.
+type ActiveTask struct {
+	Type      string `json:"type,omitempty"`
+	Node      string `json:"node,omitempty"`
+	PID       string `json:"pid,omitempty"`
+	Database  string `json:"database,omitempty"`
+	StartedOn int64  `json:"started_on,omitempty"`
+	UpdatedOn int64  `json:"updated_on,omitempty"`
+	Progress  int    `json:"progress,omitempty"`
+
+	// Compaction and indexer tasks
+	ChangesDone    int    `json:"changes_done,omitempty"`
+	TotalChanges   int    `json:"total_changes,omitempty"`
+	DesignDocument string `json:"design_document,omitempty"`
+	Phase          string `json:"phase,omitempty"`
+
+	// Replication tasks
+	ReplicationID         string          `json:"replication_id,omitempty"`
+	DocID                 string          `json:"doc_id,omitempty"`
+	Source                string          `json:"source,omitempty"`
+	Target                string          `json:"target,omitempty"`
+	Continuous            bool            `json:"continuous,omitempty"`
+	DocsRead              int             `json:"docs_read,omitempty"`
+	DocsWritten           int             `json:"docs_written,omitempty"`
+	DocWriteFailures      int             `json:"doc_write_failures,omitempty"`
+	MissingRevisionsFound int             `json:"missing_revisions_found,omitempty"`
+	RevisionsChecked      int             `json:"revisions_checked,omitempty"`
+	ChangesPending        int             `json:"changes_pending,omitempty"`
+	SourceSeq             json.RawMessage `json:"source_seq,omitempty"`
+	CheckpointedSourceSeq json.RawMessage `json:"checkpointed_source_seq,omitempty"`
+}
+
+// Completion Returns the progress of the task in percent. It relies on the
+// progress reported by CouchDB and falls back to the changes done over the
+// total changes when the progress is not reported.
+func (a ActiveTask) Completion() int {
+	if a.Progress != 0 || a.TotalChanges == 0 {
+		return a.Progress
+	}
+	return a.ChangesDone * 100 / a.TotalChanges
+}
+
+// Membership The nodes known to the node and the nodes of the cluster.
+type Membership struct {
+	AllNodes     []string `json:"all_nodes,omitempty"`
+	ClusterNodes []string `json:"cluster_nodes,omitempty"`
+}
+
+// DatabaseInfoResult A result of the _dbs_info call. Info is nil and Error is
+// set when the database does not exist.
+type DatabaseInfoResult struct {
+	Key   string        `json:"key,omitempty"`
+	Info  *DatabaseInfo `json:"info,omitempty"`
+	Error string        `json:"error,omitempty"`
+}
@@ -0,0 +1,31 @@
+package couchcandy
+
+import "context"
+
+// DB A handle on a single database. All the document, view and administration
+// methods of a database hang off it. A handle never changes once created, so
+// several handles on different databases can be used concurrently with the
+// same client.
+type DB struct {
+	handle
+}
+
+// DB Returns a handle on the named database. The handle shares the handlers of
+// the client and a copy of its session taken when DB is called.
+func (c *CouchCandy) DB(name string) *DB {
+	return &DB{handle{
+		client:  c,
+		session: withDatabase(c.Session, name),
+	}}
+}
+
+// WithContext Returns a copy of the handle whose calls are sent with the
+// context, to cancel them or to propagate the traceparent it carries.
+func (db *DB) WithContext(ctx context.Context) *DB {
+	return &DB{db.handle.withContext(ctx)}
+}
+
+// Name Returns the name of the database the handle points to.
+func (db *DB) Name() string {
+	return db.session.Database
+}
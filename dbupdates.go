@@ -0,0 +1,99 @@
+package couchcandy
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/url"
+	"strings"
+)
+
+const (
+	// FeedNormal returns the updates that happened so far and ends
+	FeedNormal string = "normal"
+	// FeedLongPoll waits for at least one update before returning
+	FeedLongPoll string = "longpoll"
+	// FeedContinuous keeps the connection open and sends updates as they happen
+	FeedContinuous string = "continuous"
+)
+
+const (
+	// DBCreated is the type of a database creation update
+	DBCreated string = "created"
+	// DBUpdated is the type of a database update
+	DBUpdated string = "updated"
+	// DBDeleted is the type of a database deletion update
+	DBDeleted string = "deleted"
+)
+
+// DBUpdatesOptions Options available when following the _db_updates feed.
+// Feed : one of FeedNormal, FeedLongPoll or FeedContinuous, FeedNormal when empty
+// Since : the sequence to start from, "now" to only get the coming updates
+// Timeout : milliseconds to wait for an update before closing a request
+// Heartbeat : milliseconds after which an empty line is sent to keep the connection alive
+type DBUpdatesOptions struct {
+	Feed      string
+	Since     string
+	Timeout   int
+	Heartbeat int
+}
+
+// DBUpdate An event of the _db_updates feed.
+type DBUpdate struct {
+	DBName string `json:"db_name,omitempty"`
+	Type   string `json:"type,omitempty"`
+	Seq    string `json:"seq,omitempty"`
+}
+
+// DBUpdates Follows the creations, updates and deletions of the databases of
+// the server. The updates are sent on the returned channel, which is closed
+// when the feed ends : after the current updates in normal mode, when the
+// context is done in longpoll and continuous modes, or when an error occurs.
+// In that last case the error is sent on the error channel before it is closed.
+func (s *Server) DBUpdates(ctx context.Context, options DBUpdatesOptions) (<-chan DBUpdate, <-chan error) {
+
+	updates := make(chan DBUpdate)
+	errs := make(chan error, 1)
+
+	go func() {
+
+		defer close(errs)
+		defer close(updates)
+
+		open := func(ctx context.Context, since string) (io.ReadCloser, error) {
+			options.Since = since
+			url := fmt.Sprintf("%s/_db_updates%s", createBaseURL(s.session), toDBUpdatesQueryString(options))
+			return s.streamFeed(ctx, url)
+		}
+
+		err := followFeed(ctx, options.Feed, options.Since, open, updates)
+		if err != nil {
+			errs <- err
+		}
+
+	}()
+
+	return updates, errs
+
+}
+
+func toDBUpdatesQueryString(options DBUpdatesOptions) string {
+
+	feed := options.Feed
+	if feed == "" {
+		feed = FeedNormal
+	}
+
+	parameters := []string{fmt.Sprintf("feed=%s", feed)}
+	if options.Since != "" {
+		parameters = append(parameters, fmt.Sprintf("since=%s", url.QueryEscape(options.Since)))
+	}
+	if options.Timeout != 0 {
+		parameters = append(parameters, fmt.Sprintf("timeout=%d", options.Timeout))
+	}
+	if options.Heartbeat != 0 {
+		parameters = append(parameters, fmt.Sprintf("heartbeat=%d", options.Heartbeat))
+	}
+	return "?" + strings.Join(parameters, "&")
+
+}
@@ -0,0 +1,309 @@
+package couchcandy
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+)
+
+const (
+	// LanguageJavaScript is the language of map/reduce design documents
+	LanguageJavaScript string = "javascript"
+	// LanguageQuery is the language of the design documents holding Mango indexes
+	LanguageQuery string = "query"
+)
+
+const (
+	// ReduceSum is the built-in reduce function summing the values
+	ReduceSum string = "_sum"
+	// ReduceCount is the built-in reduce function counting the rows
+	ReduceCount string = "_count"
+	// ReduceStats is the built-in reduce function computing sum, count, min, max and sumsqr
+	ReduceStats string = "_stats"
+	// ReduceApproxCountDistinct is the built-in reduce function approximating the number of distinct keys
+	ReduceApproxCountDistinct string = "_approx_count_distinct"
+)
+
+// DesignDocument A design document. Views hold the map/reduce functions, or
+// the Mango index definitions when Language is LanguageQuery. The functions
+// of the other fields are JavaScript sources keyed by their name. Keys
+// CouchDB or other tools may have added to the document are kept as is.
+type DesignDocument struct {
+	ID                string            `json:"_id,omitempty"`
+	REV               string            `json:"_rev,omitempty"`
+	Language          string            `json:"language,omitempty"`
+	Views             map[string]View   `json:"views,omitempty"`
+	ValidateDocUpdate string            `json:"validate_doc_update,omitempty"`
+	Filters           map[string]string `json:"filters,omitempty"`
+	Updates           map[string]string `json:"updates,omitempty"`
+	Shows             map[string]string `json:"shows,omitempty"`
+	Lists             map[string]string `json:"lists,omitempty"`
+	Rewrites          json.RawMessage   `json:"rewrites,omitempty"`
+	AutoUpdate        *bool             `json:"autoupdate,omitempty"`
+	Options           *DesignOptions    `json:"options,omitempty"`
+	extra             map[string]json.RawMessage
+}
+
+// designDocumentFields has the fields of DesignDocument, without its JSON methods.
+type designDocumentFields DesignDocument
+
+// designDocumentKeys are the keys of the fields of DesignDocument.
+var designDocumentKeys = []string{
+	"_id", "_rev", "language", "views", "validate_doc_update", "filters",
+	"updates", "shows", "lists", "rewrites", "autoupdate", "options",
+}
+
+// DesignOptions The options of a design document.
+// LocalSeq : includes the local sequence of the documents in the map function
+// IncludeDesign : also passes the design documents to the map functions
+// Partitioned : whether the views are partitioned, the database default when nil
+type DesignOptions struct {
+	LocalSeq      bool  `json:"local_seq,omitempty"`
+	IncludeDesign bool  `json:"include_design,omitempty"`
+	Partitioned   *bool `json:"partitioned,omitempty"`
+}
+
+// View A view of a design document. Reduce is either the source of a reduce
+// function or one of the built-in ones, ReduceSum, ReduceCount, ReduceStats
+// or ReduceApproxCountDistinct. Options are only set on Mango indexes. The
+// other keys, like the CommonJS modules of the "lib" entry of the views, are
+// kept as is.
+type View struct {
+	Map     ViewMap      `json:"map"`
+	Reduce  string       `json:"reduce,omitempty"`
+	Options *ViewOptions `json:"options,omitempty"`
+	extra   map[string]json.RawMessage
+}
+
+// ViewMap The map of a view : the source of a JavaScript map function, or
+// the indexed fields when the view is a Mango index.
+type ViewMap struct {
+	Function string
+	Index    *IndexMap
+}
+
+// IndexMap The map of a Mango index.
+type IndexMap struct {
+	Fields                IndexFields            `json:"fields"`
+	PartialFilterSelector map[string]interface{} `json:"partial_filter_selector,omitempty"`
+}
+
+// IndexFields The fields of a Mango index with their sort order. The fields
+// are kept in their original order, which is significant to the index.
+type IndexFields []IndexField
+
+// IndexField A field of a Mango index, Order being "asc" or "desc".
+type IndexField struct {
+	Name  string
+	Order string
+}
+
+// ViewOptions The options of a Mango index.
+type ViewOptions struct {
+	Def IndexDef `json:"def"`
+}
+
+// IndexDef The definition of a Mango index as it was created. Fields are
+// either field names or objects mapping a field name to its sort order.
+type IndexDef struct {
+	Fields                []interface{}          `json:"fields"`
+	PartialFilterSelector map[string]interface{} `json:"partial_filter_selector,omitempty"`
+}
+
+// IsIndex Tells if the design document holds Mango indexes rather than
+// map/reduce views.
+func (d *DesignDocument) IsIndex() bool {
+	return d.Language == LanguageQuery
+}
+
+// HasMap Tells if the view has a map function or index, which the "lib"
+// entry of the views holding CommonJS modules has not.
+func (v View) HasMap() bool {
+	return v.Map.Function != "" || v.Map.Index != nil
+}
+
+// MarshalJSON writes the fields of the design document along with any other
+// key read from CouchDB.
+func (d DesignDocument) MarshalJSON() ([]byte, error) {
+
+	body, err := json.Marshal(designDocumentFields(d))
+	if err != nil {
+		return nil, err
+	}
+	return withExtraKeys(body, d.extra)
+
+}
+
+// UnmarshalJSON reads the fields of the design document and keeps the other keys.
+func (d *DesignDocument) UnmarshalJSON(data []byte) error {
+
+	fields := designDocumentFields{}
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return err
+	}
+
+	extra, err := extraKeys(data, designDocumentKeys...)
+	if err != nil {
+		return err
+	}
+
+	*d = DesignDocument(fields)
+	d.extra = extra
+	return nil
+
+}
+
+// viewFields are the fields of a view as they are written.
+type viewFields struct {
+	Map     *ViewMap     `json:"map,omitempty"`
+	Reduce  string       `json:"reduce,omitempty"`
+	Options *ViewOptions `json:"options,omitempty"`
+}
+
+// MarshalJSON writes the map, reduce and options of the view along with any
+// other key read from CouchDB. The map is only left out of the entries that
+// hold other keys alone, like the "lib" modules.
+func (v View) MarshalJSON() ([]byte, error) {
+
+	fields := viewFields{Map: &v.Map, Reduce: v.Reduce, Options: v.Options}
+	if !v.HasMap() && len(v.extra) != 0 {
+		fields.Map = nil
+	}
+
+	body, err := json.Marshal(fields)
+	if err != nil {
+		return nil, err
+	}
+	return withExtraKeys(body, v.extra)
+
+}
+
+// UnmarshalJSON reads the map, reduce and options of the view and keeps the
+// other keys.
+func (v *View) UnmarshalJSON(data []byte) error {
+
+	fields := viewFields{}
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return err
+	}
+
+	extra, err := extraKeys(data, "map", "reduce", "options")
+	if err != nil {
+		return err
+	}
+
+	*v = View{Reduce: fields.Reduce, Options: fields.Options, extra: extra}
+	if fields.Map != nil {
+		v.Map = *fields.Map
+	}
+	return nil
+
+}
+
+// withExtraKeys adds the extra keys to the JSON object.
+func withExtraKeys(body []byte, extra map[string]json.RawMessage) ([]byte, error) {
+
+	if len(extra) == 0 {
+		return body, nil
+	}
+
+	object := make(map[string]json.RawMessage)
+	if err := json.Unmarshal(body, &object); err != nil {
+		return nil, err
+	}
+	for key, value := range extra {
+		object[key] = value
+	}
+	return json.Marshal(object)
+
+}
+
+// extraKeys returns the keys of the JSON object other than the known ones,
+// or nil when there are none.
+func extraKeys(data []byte, known ...string) (map[string]json.RawMessage, error) {
+
+	object := make(map[string]json.RawMessage)
+	if err := json.Unmarshal(data, &object); err != nil {
+		return nil, err
+	}
+	for _, key := range known {
+		delete(object, key)
+	}
+	if len(object) == 0 {
+		return nil, nil
+	}
+	return object, nil
+
+}
+
+// MarshalJSON writes the function source as a string or the index map as an object.
+func (m ViewMap) MarshalJSON() ([]byte, error) {
+	if m.Index != nil {
+		return json.Marshal(m.Index)
+	}
+	return json.Marshal(m.Function)
+}
+
+// UnmarshalJSON reads either a function source or an index map.
+func (m *ViewMap) UnmarshalJSON(data []byte) error {
+
+	if trimmed := bytes.TrimSpace(data); len(trimmed) > 0 && trimmed[0] == '{' {
+		m.Index = &IndexMap{}
+		return json.Unmarshal(trimmed, m.Index)
+	}
+
+	return json.Unmarshal(data, &m.Function)
+
+}
+
+// MarshalJSON writes the fields as an object, in order.
+func (f IndexFields) MarshalJSON() ([]byte, error) {
+
+	buffer := bytes.NewBufferString("{")
+	for i, field := range f {
+		if i > 0 {
+			buffer.WriteString(",")
+		}
+		name, _ := json.Marshal(field.Name)
+		order, _ := json.Marshal(field.Order)
+		buffer.Write(name)
+		buffer.WriteString(":")
+		buffer.Write(order)
+	}
+	buffer.WriteString("}")
+	return buffer.Bytes(), nil
+
+}
+
+// UnmarshalJSON reads the fields object, keeping the order of its keys.
+func (f *IndexFields) UnmarshalJSON(data []byte) error {
+
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	token, err := decoder.Token()
+	if err != nil {
+		return err
+	}
+	if delim, ok := token.(json.Delim); !ok || delim != '{' {
+		return fmt.Errorf("index fields must be an object, got %v", token)
+	}
+
+	fields := make(IndexFields, 0)
+	for decoder.More() {
+
+		name, err := decoder.Token()
+		if err != nil {
+			return err
+		}
+
+		var order string
+		if err = decoder.Decode(&order); err != nil {
+			return err
+		}
+		fields = append(fields, IndexField{Name: name.(string), Order: order})
+
+	}
+
+	*f = fields
+	return nil
+
+}
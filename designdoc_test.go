@@ -0,0 +1,127 @@
+package couchcandy
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const fullDesignDoc = `{
+	"_id": "_design/cards",
+	"_rev": "4-d2f5e1a0",
+	"language": "javascript",
+	"views": {
+		"by_suit": {"map": "function (doc) { emit(doc.suit, doc.numericValue); }", "reduce": "_stats"},
+		"distinct_names": {"map": "function (doc) { emit(doc.name, null); }", "reduce": "_approx_count_distinct"}
+	},
+	"validate_doc_update": "function (newDoc, oldDoc, userCtx) { if (!newDoc.suit) { throw({forbidden: 'suit required'}); } }",
+	"filters": {"spades": "function (doc, req) { return doc.suit == 'spades'; }"},
+	"updates": {"touch": "function (doc, req) { doc.touched = true; return [doc, 'ok']; }"},
+	"shows": {"card": "function (doc, req) { return doc.name; }"},
+	"lists": {"names": "function (head, req) { var row; while (row = getRow()) { send(row.key); } }"},
+	"rewrites": [{"from": "/cards", "to": "_view/by_suit"}],
+	"autoupdate": false,
+	"options": {"local_seq": true, "partitioned": false}
+}`
+
+func TestDesignDocumentRoundTrip(t *testing.T) {
+
+	designDoc := &DesignDocument{}
+	assert.Nil(t, json.Unmarshal([]byte(fullDesignDoc), designDoc))
+
+	assert.Equal(t, ReduceStats, designDoc.Views["by_suit"].Reduce)
+	assert.Equal(t, ReduceApproxCountDistinct, designDoc.Views["distinct_names"].Reduce)
+	assert.Contains(t, designDoc.ValidateDocUpdate, "forbidden")
+	assert.Contains(t, designDoc.Filters, "spades")
+	assert.Contains(t, designDoc.Updates, "touch")
+	assert.Contains(t, designDoc.Shows, "card")
+	assert.Contains(t, designDoc.Lists, "names")
+	assert.False(t, *designDoc.AutoUpdate)
+	assert.True(t, designDoc.Options.LocalSeq)
+	assert.False(t, *designDoc.Options.Partitioned)
+
+	body, err := json.Marshal(designDoc)
+	assert.Nil(t, err)
+	assert.JSONEq(t, fullDesignDoc, string(body))
+
+}
+
+func TestDesignDocumentKeepsUnknownKeys(t *testing.T) {
+
+	source := `{
+		"_id": "_design/cards",
+		"views": {
+			"lib": {"suits": "exports.all = ['spades', 'hearts', 'diamonds', 'clubs'];"},
+			"by_suit": {"map": "function (doc) { emit(doc.suit, 1); }", "reduce": "_count", "collation": "raw"}
+		},
+		"autoupdate": false,
+		"options": {"partitioned": true},
+		"validate_doc_update": "function (newDoc) {}",
+		"owner": {"team": "games"}
+	}`
+
+	designDoc := &DesignDocument{}
+	assert.Nil(t, json.Unmarshal([]byte(source), designDoc))
+	assert.False(t, designDoc.Views["lib"].HasMap())
+	assert.True(t, designDoc.Views["by_suit"].HasMap())
+	assert.True(t, *designDoc.Options.Partitioned)
+
+	body, err := json.Marshal(designDoc)
+	assert.Nil(t, err)
+	assert.JSONEq(t, source, string(body))
+
+	// A view built in Go is written with its map, even when empty.
+	body, err = json.Marshal(View{})
+	assert.Nil(t, err)
+	assert.JSONEq(t, `{"map":""}`, string(body))
+
+}
+
+func TestIndexDesignDocumentKeepsFieldOrder(t *testing.T) {
+
+	index := `{"_id":"_design/by-date","language":"query","views":{"date-index":{"map":{"fields":{"year":"desc","month":"asc","day":"asc"},"partial_filter_selector":{"type":"event"}},"reduce":"_count","options":{"def":{"fields":[{"year":"desc"},"month","day"],"partial_filter_selector":{"type":"event"}}}}}}`
+
+	designDoc := &DesignDocument{}
+	assert.Nil(t, json.Unmarshal([]byte(index), designDoc))
+	assert.True(t, designDoc.IsIndex())
+
+	view := designDoc.Views["date-index"]
+	assert.Equal(t, IndexFields{{"year", "desc"}, {"month", "asc"}, {"day", "asc"}}, view.Map.Index.Fields)
+	assert.Len(t, view.Options.Def.Fields, 3)
+
+	body, err := json.Marshal(designDoc)
+	assert.Nil(t, err)
+	assert.Equal(t, index, string(body))
+
+}
+
+func TestLoadFullDesignDocs(t *testing.T) {
+
+	dir := t.TempDir()
+	files := map[string]string{
+		"cards/validate_doc_update.js":  "function (newDoc) {}",
+		"cards/filters/spades.js":       "function (doc) { return doc.suit == 'spades'; }",
+		"cards/shows/card.js":           "function (doc) { return doc.name; }",
+		"cards/options.json":            `{"local_seq":true}`,
+		"cards/views/by_suit/map.js":    "function (doc) { emit(doc.suit, 1); }",
+		"cards/views/by_suit/reduce.js": "_sum",
+	}
+	for path, content := range files {
+		path = filepath.Join(dir, path)
+		assert.Nil(t, os.MkdirAll(filepath.Dir(path), 0755))
+		assert.Nil(t, os.WriteFile(path, []byte(content), 0644))
+	}
+
+	designDocs, err := LoadDesignDocs(dir)
+	assert.Nil(t, err)
+	assert.Len(t, designDocs, 1)
+	assert.Equal(t, "function (newDoc) {}", designDocs[0].ValidateDocUpdate)
+	assert.Contains(t, designDocs[0].Filters["spades"], "spades")
+	assert.Contains(t, designDocs[0].Shows, "card")
+	assert.True(t, designDocs[0].Options.LocalSeq)
+	assert.Equal(t, ReduceSum, designDocs[0].Views["by_suit"].Reduce)
+
+}
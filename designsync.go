@@ -0,0 +1,345 @@
+package couchcandy
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+const (
+	// designPrefix is the id prefix of design documents
+	designPrefix string = "_design/"
+	// stagedSuffix is appended to the name of a staged design document
+	stagedSuffix string = "_new"
+)
+
+// DesignDocSync Brings the design documents of a database in line with a
+// desired set. Only the design documents that differ are written, so that
+// unchanged views keep their index.
+//
+// When Staged is set, a changed design document is first written under a
+// temporary "_design/name_new" id and its index is built, see WaitForIndex,
+// while the current one keeps serving queries. The content is then copied
+// over the real design document, which picks up the already built index
+// since both share the same signature, and the staged design document is
+// removed.
+type DesignDocSync struct {
+	Staged bool
+	db     *DB
+}
+
+// SyncResult Lists the design document ids created, updated and left
+// unchanged by a synchronisation.
+type SyncResult struct {
+	Created   []string
+	Updated   []string
+	Unchanged []string
+}
+
+// DesignDocSync Returns the synchronisation of the design documents of the database.
+func (db *DB) DesignDocSync() *DesignDocSync {
+	return &DesignDocSync{db: db}
+}
+
+// Sync Creates or updates the design documents that differ from the desired
+// ones. The ids of the desired design documents can be given with or without
+// the "_design/" prefix. The keys of a desired design document replace the
+// ones of the design document stored, the keys it does not have, like the
+// ones added by other tools, are kept. Design documents that are not in the
+// desired set are left untouched. The requests are sent with the context, which also bounds
+// the build of the staged indexes.
+func (s *DesignDocSync) Sync(ctx context.Context, desired []*DesignDocument) (*SyncResult, error) {
+
+	s = &DesignDocSync{Staged: s.Staged, db: s.db.WithContext(ctx)}
+
+	designDocs, err := s.db.DesignDocs()
+	if err != nil {
+		return nil, err
+	}
+
+	existing := make(map[string]*DesignDocument)
+	for _, designDoc := range designDocs {
+		if designDoc.Language == "" {
+			designDoc.Language = LanguageJavaScript
+		}
+		existing[designDoc.ID] = designDoc
+	}
+
+	result := &SyncResult{
+		Created:   make([]string, 0),
+		Updated:   make([]string, 0),
+		Unchanged: make([]string, 0),
+	}
+
+	for _, designDoc := range desired {
+
+		wanted := *designDoc
+		wanted.ID = designPrefix + strings.TrimPrefix(designDoc.ID, designPrefix)
+		if wanted.Language == "" {
+			wanted.Language = LanguageJavaScript
+		}
+
+		current, found := existing[wanted.ID]
+		if found {
+			merged, err := mergeDesignDoc(current, &wanted)
+			if err != nil {
+				return result, err
+			}
+			if sameDesignDoc(current, merged) {
+				result.Unchanged = append(result.Unchanged, wanted.ID)
+				continue
+			}
+			wanted = *merged
+		}
+
+		stagedID := wanted.ID + stagedSuffix
+		stagedRev := ""
+		if staged, ok := existing[stagedID]; ok {
+			stagedRev = staged.REV
+		}
+
+		if found && s.Staged {
+			if stagedRev, err = s.stage(ctx, &wanted, stagedRev); err != nil {
+				return result, err
+			}
+		}
+
+		wanted.REV = ""
+		if found {
+			wanted.REV = current.REV
+		}
+		if err = s.put(&wanted); err != nil {
+			return result, err
+		}
+
+		if stagedRev != "" {
+			if err = s.removeStaged(stagedID, stagedRev); err != nil {
+				return result, err
+			}
+		}
+
+		if found {
+			result.Updated = append(result.Updated, wanted.ID)
+		} else {
+			result.Created = append(result.Created, wanted.ID)
+		}
+
+	}
+
+	return result, nil
+
+}
+
+// stage writes the design document under its staged id and waits for its
+// index to be built. It returns the revision of the staged design document.
+func (s *DesignDocSync) stage(ctx context.Context, designDoc *DesignDocument, rev string) (string, error) {
+
+	staged := *designDoc
+	staged.ID = designDoc.ID + stagedSuffix
+	staged.REV = rev
+
+	if err := s.put(&staged); err != nil {
+		return "", err
+	}
+
+	if staged.IsIndex() {
+		return staged.REV, nil
+	}
+
+	// All the views of a design document share a single index.
+	for _, view := range staged.Views {
+		if view.HasMap() {
+			if err := s.db.WaitForIndex(ctx, staged.ID); err != nil {
+				return staged.REV, fmt.Errorf("building the index of %s: %v", staged.ID, err)
+			}
+			break
+		}
+	}
+
+	return staged.REV, nil
+
+}
+
+// removeStaged deletes the staged design document once the real one holds
+// its content, or when it was left behind by a previous synchronisation.
+func (s *DesignDocSync) removeStaged(id, rev string) error {
+
+	response, err := s.db.DeleteDocument(id, rev)
+	if err != nil {
+		return err
+	}
+	if response.Error != "" {
+		return fmt.Errorf("deleting %s: %s: %s", id, response.Error, response.Reason)
+	}
+	return nil
+
+}
+
+func (s *DesignDocSync) put(designDoc *DesignDocument) error {
+
+	response, err := s.db.AddWithID(designDoc.ID, designDoc)
+	if err != nil {
+		return err
+	}
+	if response.Error != "" {
+		return fmt.Errorf("writing %s: %s: %s", designDoc.ID, response.Error, response.Reason)
+	}
+	designDoc.REV = response.REV
+	return nil
+
+}
+
+// mergeDesignDoc lays the keys of the wanted design document over the ones
+// of the current one.
+func mergeDesignDoc(current, wanted *DesignDocument) (*DesignDocument, error) {
+
+	keys := make(map[string]json.RawMessage)
+	for _, designDoc := range []*DesignDocument{current, wanted} {
+		body, err := json.Marshal(designDoc)
+		if err != nil {
+			return nil, err
+		}
+		if err = json.Unmarshal(body, &keys); err != nil {
+			return nil, err
+		}
+	}
+
+	body, err := json.Marshal(keys)
+	if err != nil {
+		return nil, err
+	}
+
+	merged := &DesignDocument{}
+	if err = json.Unmarshal(body, merged); err != nil {
+		return nil, err
+	}
+	merged.REV = current.REV
+	return merged, nil
+
+}
+
+// sameDesignDoc compares the whole JSON form of both design documents, the
+// keys unknown to DesignDocument included, ignoring their revisions.
+func sameDesignDoc(a, b *DesignDocument) bool {
+
+	left, right := *a, *b
+	left.REV, right.REV = "", ""
+
+	leftJSON, leftError := json.Marshal(&left)
+	rightJSON, rightError := json.Marshal(&right)
+	return leftError == nil && rightError == nil && string(leftJSON) == string(rightJSON)
+
+}
+
+// LoadDesignDocs Reads design documents from a directory. Every sub directory
+// is a design document named after it, every nested directory a JSON object
+// and every file a key of that object : ".js" files hold the source of a
+// function and ".json" files a JSON value. For instance the map function of
+// the byType view of the cards design document is read from
+// "cards/views/byType/map.js". A "name.json" file at the root of the
+// directory holds a whole design document instead.
+func LoadDesignDocs(dir string) ([]*DesignDocument, error) {
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	designDocs := make([]*DesignDocument, 0, len(entries))
+	for _, entry := range entries {
+
+		var value interface{}
+		name := entry.Name()
+		path := filepath.Join(dir, name)
+
+		switch {
+		case entry.IsDir():
+			value, err = loadDesignDocDir(path)
+		case filepath.Ext(name) == ".json":
+			value, err = loadDesignDocFile(path)
+			name = strings.TrimSuffix(name, ".json")
+		default:
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		body, err := json.Marshal(value)
+		if err != nil {
+			return nil, err
+		}
+
+		designDoc := &DesignDocument{}
+		if err = json.Unmarshal(body, designDoc); err != nil {
+			return nil, fmt.Errorf("%s: %v", path, err)
+		}
+		if designDoc.ID == "" {
+			designDoc.ID = designPrefix + name
+		}
+		designDocs = append(designDocs, designDoc)
+
+	}
+
+	sort.Slice(designDocs, func(i, j int) bool { return designDocs[i].ID < designDocs[j].ID })
+	return designDocs, nil
+
+}
+
+func loadDesignDocDir(dir string) (map[string]interface{}, error) {
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	object := make(map[string]interface{}, len(entries))
+	for _, entry := range entries {
+
+		path := filepath.Join(dir, entry.Name())
+		key := strings.TrimSuffix(entry.Name(), filepath.Ext(entry.Name()))
+
+		if entry.IsDir() {
+			if object[entry.Name()], err = loadDesignDocDir(path); err != nil {
+				return nil, err
+			}
+			continue
+		}
+
+		switch filepath.Ext(entry.Name()) {
+		case ".js":
+			source, err := os.ReadFile(path)
+			if err != nil {
+				return nil, err
+			}
+			object[key] = strings.TrimSpace(string(source))
+		case ".json":
+			if object[key], err = loadDesignDocFile(path); err != nil {
+				return nil, err
+			}
+		}
+
+	}
+
+	return object, nil
+
+}
+
+func loadDesignDocFile(path string) (interface{}, error) {
+
+	body, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var value interface{}
+	if err = json.Unmarshal(body, &value); err != nil {
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+	return value, nil
+
+}
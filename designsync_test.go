@@ -0,0 +1,173 @@
+package couchcandy
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const syncDesignDocs = `{"total_rows":3,"offset":0,"rows":[
+{"id":"_design/cards","key":"_design/cards","value":{"rev":"1-a"},"doc":{"_id":"_design/cards","_rev":"1-a","language":"javascript","views":{"by_suit":{"map":"function (doc) { emit(doc.suit, 1); }","reduce":"_count"}},"autoupdate":false,"owner":"cards"}},
+{"id":"_design/players","key":"_design/players","value":{"rev":"3-b"},"doc":{"_id":"_design/players","_rev":"3-b","views":{"by_name":{"map":"function (doc) { emit(doc.name, null); }"}},"owner":"players"}}
+]}`
+
+// syncServer serves the design documents of syncDesignDocs and records the
+// bodies of the design documents written, by path.
+func syncServer(couchcandy *CouchCandy) (*router, map[string]string) {
+
+	written := make(map[string]string)
+	put := func(req *http.Request, body []byte) (*http.Response, error) {
+		written[req.URL.Path] = string(body)
+		return statusResponse(http.StatusCreated, `{"ok":true,"id":"x","rev":"9-z"}`), nil
+	}
+
+	server := route(couchcandy).
+		Reply(http.MethodGet, "/lendr/_all_docs", http.StatusOK, syncDesignDocs).
+		Reply(http.MethodGet, "/lendr/_design/players_new", http.StatusOK, `{"_id":"_design/players_new","_rev":"9-z","views":{"by_name":{"map":"function (doc) { emit(doc.name, doc.team); }"}}}`).
+		Reply(http.MethodGet, "/lendr/_design/players_new/_view/by_name", http.StatusOK, `{"total_rows":0,"offset":0,"rows":[]}`).
+		Reply(http.MethodGet, "/_active_tasks", http.StatusOK, `[]`).
+		Reply(http.MethodGet, "/lendr/_design/players_new/_info", http.StatusOK, `{"name":"players_new","view_index":{"updater_running":false}}`).
+		Reply(http.MethodDelete, "/lendr/_design/players_new", http.StatusOK, `{"ok":true}`)
+	for _, name := range []string{"cards", "players", "players_new", "teams"} {
+		server.Handle(http.MethodPut, "/lendr/_design/"+name, put)
+	}
+	return server, written
+
+}
+
+func TestDesignDocSync(t *testing.T) {
+
+	couchcandy := newTestClient()
+	server, written := syncServer(couchcandy)
+
+	result, err := couchcandy.DB("lendr").DesignDocSync().Sync(context.Background(), []*DesignDocument{
+		{ID: "cards", Views: map[string]View{"by_suit": {Map: ViewMap{Function: "function (doc) { emit(doc.suit, 1); }"}, Reduce: "_count"}}},
+		{ID: "_design/players", Views: map[string]View{"by_name": {Map: ViewMap{Function: "function (doc) { emit(doc.name, doc.team); }"}}}},
+		{ID: "teams", Views: map[string]View{"by_city": {Map: ViewMap{Function: "function (doc) { emit(doc.city, null); }"}}}},
+	})
+
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"_design/cards"}, result.Unchanged)
+	assert.Equal(t, []string{"_design/players"}, result.Updated)
+	assert.Equal(t, []string{"_design/teams"}, result.Created)
+
+	assert.Equal(t, []string{
+		"PUT /lendr/_design/players?",
+		"PUT /lendr/_design/teams?",
+	}, server.Requests()[1:])
+	assert.Contains(t, written["/lendr/_design/players"], `"_rev":"3-b"`)
+	assert.Contains(t, written["/lendr/_design/players"], `"owner":"players"`)
+	assert.Contains(t, written["/lendr/_design/players"], `emit(doc.name, doc.team)`)
+	assert.NotContains(t, written["/lendr/_design/teams"], `"_rev"`)
+
+}
+
+func TestDesignDocSyncUnknownKeys(t *testing.T) {
+
+	couchcandy := newTestClient()
+	_, written := syncServer(couchcandy)
+
+	cards := &DesignDocument{}
+	if err := json.Unmarshal([]byte(`{"_id":"cards","views":{"by_suit":{"map":"function (doc) { emit(doc.suit, 1); }","reduce":"_count"}},"owner":"lendr"}`), cards); err != nil {
+		t.Fatal(err)
+	}
+
+	result, err := couchcandy.DB("lendr").DesignDocSync().Sync(context.Background(), []*DesignDocument{cards})
+
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"_design/cards"}, result.Updated)
+	assert.Contains(t, written["/lendr/_design/cards"], `"owner":"lendr"`)
+	assert.Contains(t, written["/lendr/_design/cards"], `"autoupdate":false`)
+	assert.Contains(t, written["/lendr/_design/cards"], `"_rev":"1-a"`)
+
+}
+
+func TestDesignDocSyncStaged(t *testing.T) {
+
+	couchcandy := newTestClient()
+	server, written := syncServer(couchcandy)
+
+	sync := couchcandy.DB("lendr").DesignDocSync()
+	sync.Staged = true
+	result, err := sync.Sync(context.Background(), []*DesignDocument{
+		{ID: "players", Views: map[string]View{"by_name": {Map: ViewMap{Function: "function (doc) { emit(doc.name, doc.team); }"}}}},
+	})
+
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"_design/players"}, result.Updated)
+	assert.Equal(t, []string{
+		"PUT /lendr/_design/players_new?",
+		"GET /lendr/_design/players_new?",
+		"GET /lendr/_design/players_new/_view/by_name?limit=0&update=lazy",
+		"GET /_active_tasks?",
+		"GET /lendr/_design/players_new/_info?",
+		"GET /lendr/_design/players_new/_view/by_name?limit=0",
+		"PUT /lendr/_design/players?",
+		"DELETE /lendr/_design/players_new?rev=9-z",
+	}, server.Requests()[1:])
+	assert.NotContains(t, written["/lendr/_design/players_new"], `"_rev"`)
+	assert.Contains(t, written["/lendr/_design/players"], `"_rev":"3-b"`)
+
+}
+
+func TestDesignDocSyncFailure(t *testing.T) {
+
+	couchcandy := newTestClient()
+	route(couchcandy).
+		Reply(http.MethodGet, "/lendr/_all_docs", http.StatusOK, `{"rows":[]}`).
+		Reply(http.MethodPut, "/lendr/_design/cards", http.StatusForbidden, `{"error":"forbidden","reason":"Only admins may write design documents."}`)
+
+	_, err := couchcandy.DB("lendr").DesignDocSync().Sync(context.Background(), []*DesignDocument{{ID: "cards"}})
+	assert.EqualError(t, err, "writing _design/cards: forbidden: Only admins may write design documents.")
+
+}
+
+func TestLoadDesignDocs(t *testing.T) {
+
+	dir := t.TempDir()
+	write := func(path, content string) {
+		path = filepath.Join(dir, path)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write("cards/views/byType/map.js", "function (doc) {\n  emit(doc.type, 1);\n}\n")
+	write("cards/views/byType/reduce.js", "_count\n")
+	write("cards/views/bySuit/map.js", "function (doc) { emit(doc.suit, null); }")
+	write("cards/README.md", "ignored")
+	write("players.json", `{"language":"javascript","views":{"by_name":{"map":"function (doc) { emit(doc.name, null); }"}}}`)
+
+	designDocs, err := LoadDesignDocs(dir)
+	assert.Nil(t, err)
+	assert.Len(t, designDocs, 2)
+
+	assert.Equal(t, "_design/cards", designDocs[0].ID)
+	assert.Equal(t, "function (doc) {\n  emit(doc.type, 1);\n}", designDocs[0].Views["byType"].Map.Function)
+	assert.Equal(t, "_count", designDocs[0].Views["byType"].Reduce)
+	assert.Equal(t, "", designDocs[0].Views["bySuit"].Reduce)
+	assert.Equal(t, "_design/players", designDocs[1].ID)
+	assert.Equal(t, "javascript", designDocs[1].Language)
+
+}
+
+func TestLoadDesignDocsInvalidJSON(t *testing.T) {
+
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "broken.json"), []byte("{"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	_, err := LoadDesignDocs(dir)
+	assert.NotNil(t, err)
+	assert.Contains(t, err.Error(), fmt.Sprintf("%c%s", filepath.Separator, "broken.json"))
+
+}
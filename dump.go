@@ -0,0 +1,315 @@
+package couchcandy
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// DefaultBatchSize is the number of documents read or written per request
+// when none is given
+const DefaultBatchSize int = 500
+
+// A dump holds a JSON document per line, with its revision history in
+// _revisions, like the documents couchimport reads. When included, the
+// security object comes first, on a line of its own under the "_security"
+// key, which cannot be the field of a document. Restore also reads the lines
+// holding an array of documents written by couchbackup.
+
+// DumpOptions Options available when dumping a database.
+// Attachments : includes the content of the attachments, base64 encoded, instead of leaving them out
+// DesignDocs : includes the design documents
+// Security : includes the security object
+// BatchSize : number of documents read per request, DefaultBatchSize when 0
+type DumpOptions struct {
+	Attachments bool
+	DesignDocs  bool
+	Security    bool
+	BatchSize   int
+}
+
+// DumpResult The outcome of a dump. Seq is the update sequence of the
+// database when the dump started, the changes that follow it may or may not
+// be part of the dump.
+type DumpResult struct {
+	Documents int
+	Seq       string
+}
+
+// RestoreOptions Options available when restoring a dump.
+// Security : restores the security object of the dump, if any
+// BatchSize : number of documents written per request, DefaultBatchSize when 0
+type RestoreOptions struct {
+	Security  bool
+	BatchSize int
+}
+
+// RestoreResult The outcome of a restore. Failed lists the documents that
+// were not written, with the reason.
+type RestoreResult struct {
+	Documents int
+	Failed    []OperationResponse
+}
+
+// securityLine is the line of a dump holding the security object.
+type securityLine struct {
+	ID       string          `json:"_id,omitempty"`
+	Security json.RawMessage `json:"_security,omitempty"`
+}
+
+// Dump Writes the current revision of the documents of the database to w,
+// one per line, with their revision history. Deleted and local documents are
+// left out, and so are the attachments unless asked for, as a restored
+// document cannot refer to an attachment that is not in the dump.
+func (db *DB) Dump(w io.Writer, options DumpOptions) (*DumpResult, error) {
+
+	seq, err := db.updateSeq()
+	if err != nil {
+		return nil, err
+	}
+	result := &DumpResult{Seq: seq}
+
+	if options.Security {
+		security, err := db.GetSecurity()
+		if err != nil {
+			return result, err
+		}
+		line, err := json.Marshal(map[string]*Security{"_security": security})
+		if err != nil {
+			return result, err
+		}
+		if err = writeLine(w, line); err != nil {
+			return result, err
+		}
+	}
+
+	batchSize := options.BatchSize
+	if batchSize <= 0 {
+		batchSize = DefaultBatchSize
+	}
+
+	lastID := ""
+	for {
+
+		page := Options{Limit: batchSize}
+		if lastID != "" {
+			startKey, _ := json.Marshal(lastID)
+			page.StartKey = string(startKey)
+			page.Skip = 1
+		}
+
+		allDocuments, err := db.Documents(page)
+		if err != nil {
+			return result, err
+		}
+		if allDocuments.Error != "" {
+			return result, fmt.Errorf("%s: %s", allDocuments.Error, allDocuments.Reason)
+		}
+
+		docs := make([]BulkGetDoc, 0, len(allDocuments.Rows))
+		for _, row := range allDocuments.Rows {
+			if options.DesignDocs || !strings.HasPrefix(row.ID, designPrefix) {
+				docs = append(docs, BulkGetDoc{ID: row.ID})
+			}
+		}
+
+		if len(docs) != 0 {
+			count, _, err := db.dumpDocs(w, docs, options.Attachments)
+			result.Documents += count
+			if err != nil {
+				return result, err
+			}
+		}
+
+		if len(allDocuments.Rows) < batchSize {
+			return result, nil
+		}
+		lastID = allDocuments.Rows[len(allDocuments.Rows)-1].ID
+
+	}
+
+}
+
+// dumpDocs reads the documents with their history and writes them to w.
+func (db *DB) dumpDocs(w io.Writer, docs []BulkGetDoc, attachments bool) (int, int, error) {
+
+	results, err := db.BulkGet(docs, BulkGetOptions{Revs: true, Attachments: attachments})
+	if err != nil {
+		return 0, 0, err
+	}
+
+	count, deleted := 0, 0
+	for _, result := range results {
+		for _, item := range result.Docs {
+
+			// A document deleted since it was listed is left to the next
+			// backup.
+			if item.Error != nil && item.Error.Error == "not_found" {
+				continue
+			}
+			if item.Error != nil {
+				return count, deleted, fmt.Errorf("%s: %s: %s", result.ID, item.Error.Error, item.Error.Reason)
+			}
+
+			doc := item.OK
+			if !attachments {
+				if doc, err = withoutAttachments(doc); err != nil {
+					return count, deleted, err
+				}
+			}
+			if err = writeLine(w, doc); err != nil {
+				return count, deleted, err
+			}
+			count++
+			if isDeleted(doc) {
+				deleted++
+			}
+
+		}
+	}
+	return count, deleted, nil
+
+}
+
+// isDeleted tells if the document is the tombstone of a deleted document.
+func isDeleted(doc json.RawMessage) bool {
+	tombstone := struct {
+		Deleted bool `json:"_deleted"`
+	}{}
+	return json.Unmarshal(doc, &tombstone) == nil && tombstone.Deleted
+}
+
+// withoutAttachments removes the attachment stubs of the document.
+func withoutAttachments(doc json.RawMessage) (json.RawMessage, error) {
+
+	fields := make(map[string]json.RawMessage)
+	if err := json.Unmarshal(doc, &fields); err != nil {
+		return nil, err
+	}
+	if _, ok := fields["_attachments"]; !ok {
+		return doc, nil
+	}
+	delete(fields, "_attachments")
+	return json.Marshal(fields)
+
+}
+
+// writeLine writes the JSON value on a single line.
+func writeLine(w io.Writer, value []byte) error {
+
+	line := &bytes.Buffer{}
+	if err := json.Compact(line, value); err != nil {
+		return err
+	}
+	line.WriteByte('\n')
+	_, err := w.Write(line.Bytes())
+	return err
+
+}
+
+// Restore Writes the documents of the dump read from r to the database, in
+// batches, keeping their revisions so that the restored database can still
+// replicate with the original one. The documents CouchDB rejects are
+// reported in the result, other errors stop the restore.
+func (db *DB) Restore(r io.Reader, options RestoreOptions) (*RestoreResult, error) {
+
+	result := &RestoreResult{}
+
+	batchSize := options.BatchSize
+	if batchSize <= 0 {
+		batchSize = DefaultBatchSize
+	}
+
+	batch := make([]json.RawMessage, 0, batchSize)
+	flush := func() error {
+		if len(batch) == 0 {
+			return nil
+		}
+		responses, err := db.BulkDocs(batch, BulkDocsOptions{KeepRevisions: true})
+		if err != nil {
+			return err
+		}
+		written := len(batch)
+		for _, response := range responses {
+			if response.Error != "" {
+				result.Failed = append(result.Failed, response)
+				written--
+			}
+		}
+		result.Documents += written
+		batch = batch[:0]
+		return nil
+	}
+
+	decoder := json.NewDecoder(r)
+	for {
+
+		var value json.RawMessage
+		err := decoder.Decode(&value)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return result, err
+		}
+
+		docs, err := db.restoreLine(value, options.Security)
+		if err != nil {
+			return result, err
+		}
+
+		for _, doc := range docs {
+			batch = append(batch, doc)
+			if len(batch) == batchSize {
+				if err = flush(); err != nil {
+					return result, err
+				}
+			}
+		}
+
+	}
+
+	return result, flush()
+
+}
+
+// restoreLine returns the documents of a line of a dump, restoring the
+// security object when the line holds it.
+func (db *DB) restoreLine(value json.RawMessage, restoreSecurity bool) ([]json.RawMessage, error) {
+
+	switch bytes.TrimSpace(value)[0] {
+	case '[':
+		docs := make([]json.RawMessage, 0)
+		err := json.Unmarshal(value, &docs)
+		return docs, err
+	case '{':
+	default:
+		return nil, fmt.Errorf("unexpected line in dump: %.40s", value)
+	}
+
+	line := &securityLine{}
+	if err := json.Unmarshal(value, line); err != nil {
+		return nil, err
+	}
+	if line.ID != "" || line.Security == nil {
+		return []json.RawMessage{value}, nil
+	}
+
+	if restoreSecurity {
+		security := &Security{}
+		if err := json.Unmarshal(line.Security, security); err != nil {
+			return nil, err
+		}
+		response, err := db.SetSecurity(security)
+		if err != nil {
+			return nil, err
+		}
+		if response.Error != "" {
+			return nil, fmt.Errorf("%s: %s", response.Error, response.Reason)
+		}
+	}
+	return nil, nil
+
+}
@@ -0,0 +1,112 @@
+package couchcandy
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// dumpServer serves a database of three documents and a design document,
+// two documents per _all_docs page.
+func dumpServer(t *testing.T, couchcandy *CouchCandy) *router {
+
+	return route(couchcandy).
+		Reply(http.MethodGet, "/lendr", http.StatusOK, `{"db_name":"lendr","update_seq":"3-g1AAAA"}`).
+		Reply(http.MethodGet, "/lendr/_security", http.StatusOK, `{"admins":{"names":["jan"],"roles":[]},"members":{"names":[],"roles":["players"]}}`).
+		Handle(http.MethodGet, "/lendr/_all_docs", func(req *http.Request, body []byte) (*http.Response, error) {
+			if req.URL.Query().Get("start_key") == "" {
+				return statusResponse(http.StatusOK, `{"rows":[{"id":"_design/cards","key":"_design/cards"},{"id":"card-1","key":"card-1"}]}`), nil
+			}
+			assert.Equal(t, `"card-1"`, req.URL.Query().Get("start_key"))
+			assert.Equal(t, "1", req.URL.Query().Get("skip"))
+			return statusResponse(http.StatusOK, `{"rows":[{"id":"card-2","key":"card-2"}]}`), nil
+		}).
+		Handle(http.MethodPost, "/lendr/_bulk_get", func(req *http.Request, body []byte) (*http.Response, error) {
+			request := &struct {
+				Docs []BulkGetDoc `json:"docs"`
+			}{}
+			assert.Nil(t, json.Unmarshal(body, request))
+			results := make([]string, len(request.Docs))
+			for i, doc := range request.Docs {
+				results[i] = fmt.Sprintf(`{"id":%q,"docs":[{"ok":{"_id":%q,"_rev":"2-b","_revisions":{"start":2,"ids":["b","a"]},
+					"_attachments":{"photo.png":{"content_type":"image/png","stub":true}}}}]}`, doc.ID, doc.ID)
+			}
+			return statusResponse(http.StatusOK, `{"results":[`+strings.Join(results, ",")+`]}`), nil
+		})
+
+}
+
+func TestDump(t *testing.T) {
+
+	couchcandy := newTestClient()
+	server := dumpServer(t, couchcandy)
+
+	dump := &bytes.Buffer{}
+	result, err := couchcandy.DB("lendr").Dump(dump, DumpOptions{Security: true, BatchSize: 2})
+	assert.Nil(t, err)
+	assert.Equal(t, 2, result.Documents)
+	assert.Equal(t, "3-g1AAAA", result.Seq)
+	assert.Len(t, server.Requests(), 6)
+
+	lines := strings.Split(strings.TrimSuffix(dump.String(), "\n"), "\n")
+	assert.Len(t, lines, 3)
+	assert.JSONEq(t, `{"_security":{"admins":{"names":["jan"],"roles":[]},"members":{"names":[],"roles":["players"]}}}`, lines[0])
+	assert.JSONEq(t, `{"_id":"card-1","_rev":"2-b","_revisions":{"start":2,"ids":["b","a"]}}`, lines[1])
+	assert.JSONEq(t, `{"_id":"card-2","_rev":"2-b","_revisions":{"start":2,"ids":["b","a"]}}`, lines[2])
+
+	dump.Reset()
+	result, err = couchcandy.DB("lendr").Dump(dump, DumpOptions{Attachments: true, DesignDocs: true, BatchSize: 2})
+	assert.Nil(t, err)
+	assert.Equal(t, 3, result.Documents)
+	assert.Contains(t, server.Requests()[8], "attachments=true")
+	assert.True(t, strings.HasPrefix(dump.String(), `{"_id":"_design/cards"`))
+	assert.Contains(t, dump.String(), `"_attachments":{"photo.png"`)
+
+}
+
+func TestRestore(t *testing.T) {
+
+	couchcandy := newTestClient()
+	var security string
+	batches := make([]string, 0)
+	route(couchcandy).
+		Handle(http.MethodPut, "/lendr/_security", func(req *http.Request, body []byte) (*http.Response, error) {
+			security = string(body)
+			return statusResponse(http.StatusOK, `{"ok":true}`), nil
+		}).
+		Handle(http.MethodPost, "/lendr/_bulk_docs", func(req *http.Request, body []byte) (*http.Response, error) {
+			batches = append(batches, string(body))
+			if strings.Contains(string(body), "card-3") {
+				return statusResponse(http.StatusCreated, `[{"id":"card-3","rev":"1-c","error":"forbidden","reason":"Invalid card."}]`), nil
+			}
+			return statusResponse(http.StatusCreated, `[]`), nil
+		})
+
+	dump := strings.NewReader(`{"_security":{"admins":{"names":["jan"],"roles":[]},"members":{"names":[],"roles":[]}}}
+{"_id":"card-1","_rev":"2-b","_revisions":{"start":2,"ids":["b","a"]}}
+[{"_id":"card-2","_rev":"1-a"},{"_id":"card-3","_rev":"1-c"}]
+`)
+
+	result, err := couchcandy.DB("lendr").Restore(dump, RestoreOptions{Security: true, BatchSize: 2})
+	assert.Nil(t, err)
+	assert.Equal(t, 2, result.Documents)
+	assert.Len(t, result.Failed, 1)
+	assert.Equal(t, "card-3", result.Failed[0].ID)
+	assert.JSONEq(t, `{"admins":{"names":["jan"],"roles":[]},"members":{"names":[],"roles":[]}}`, security)
+
+	assert.Len(t, batches, 2)
+	assert.JSONEq(t, `{"docs":[
+		{"_id":"card-1","_rev":"2-b","_revisions":{"start":2,"ids":["b","a"]}},
+		{"_id":"card-2","_rev":"1-a"}
+	],"new_edits":false}`, batches[0])
+	assert.JSONEq(t, `{"docs":[{"_id":"card-3","_rev":"1-c"}],"new_edits":false}`, batches[1])
+
+	_, err = couchcandy.DB("lendr").Restore(strings.NewReader(`{"_id":"card-1"} 42`), RestoreOptions{})
+	assert.NotNil(t, err)
+
+}
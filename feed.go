@@ -0,0 +1,200 @@
+package couchcandy
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// The _db_updates and _changes feeds share their format : a page of results
+// ending with last_seq in normal and longpoll modes, one event per line in
+// continuous mode.
+
+// feedPage is a page of a feed in normal or longpoll mode.
+type feedPage[T any] struct {
+	Results []T    `json:"results,omitempty"`
+	LastSeq string `json:"last_seq,omitempty"`
+	Error   string `json:"error,omitempty"`
+	Reason  string `json:"reason,omitempty"`
+}
+
+// feedEnd is the part of a continuous feed line telling that the feed ended
+// or failed.
+type feedEnd struct {
+	LastSeq string `json:"last_seq,omitempty"`
+	Error   string `json:"error,omitempty"`
+	Reason  string `json:"reason,omitempty"`
+}
+
+// openFeed requests the feed starting at the given sequence.
+type openFeed func(ctx context.Context, since string) (io.ReadCloser, error)
+
+// followFeed sends the events of the feed on the channel until it ends. The
+// returned error is nil when the feed ended because the context is done.
+func followFeed[T any](ctx context.Context, feed, since string, open openFeed, events chan<- T) error {
+
+	var err error
+	switch feed {
+	case FeedContinuous:
+		err = continuousFeed(ctx, since, open, events)
+	case FeedLongPoll:
+		err = longPollFeed(ctx, since, open, events)
+	default:
+		_, err = pollFeed(ctx, since, open, events)
+	}
+
+	if ctx.Err() != nil {
+		return nil
+	}
+	return err
+
+}
+
+// longPollFeed issues longpoll requests one after the other, each starting
+// at the last sequence returned by the previous one.
+func longPollFeed[T any](ctx context.Context, since string, open openFeed, events chan<- T) error {
+
+	for ctx.Err() == nil {
+		lastSeq, err := pollFeed(ctx, since, open, events)
+		if err != nil {
+			return err
+		}
+		if lastSeq != "" {
+			since = lastSeq
+		}
+	}
+	return nil
+
+}
+
+func pollFeed[T any](ctx context.Context, since string, open openFeed, events chan<- T) (string, error) {
+
+	body, err := open(ctx, since)
+	if err != nil {
+		return "", err
+	}
+	defer body.Close()
+
+	content, err := io.ReadAll(body)
+	if err != nil {
+		return "", err
+	}
+
+	page := &feedPage[T]{}
+	if err = json.Unmarshal(content, page); err != nil {
+		return "", err
+	}
+	if page.Error != "" {
+		return "", fmt.Errorf("%s: %s", page.Error, page.Reason)
+	}
+
+	for _, event := range page.Results {
+		if !sendEvent(ctx, events, event) {
+			return "", nil
+		}
+	}
+	return page.LastSeq, nil
+
+}
+
+// continuousFeed reads the feed line by line. Empty lines are heartbeats
+// and the line holding last_seq, if any, ends the feed.
+func continuousFeed[T any](ctx context.Context, since string, open openFeed, events chan<- T) error {
+
+	body, err := open(ctx, since)
+	if err != nil {
+		return err
+	}
+	defer body.Close()
+
+	scanner := bufio.NewScanner(body)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxFeedLine)
+	for scanner.Scan() {
+
+		line := []byte(strings.TrimSpace(scanner.Text()))
+		if len(line) == 0 {
+			continue
+		}
+
+		end := &feedEnd{}
+		if err = json.Unmarshal(line, end); err != nil {
+			return err
+		}
+		if end.Error != "" {
+			return fmt.Errorf("%s: %s", end.Error, end.Reason)
+		}
+		if end.LastSeq != "" {
+			return nil
+		}
+
+		var event T
+		if err = json.Unmarshal(line, &event); err != nil {
+			return err
+		}
+		if !sendEvent(ctx, events, event) {
+			return nil
+		}
+
+	}
+	return scanner.Err()
+
+}
+
+// maxFeedLine bounds a line of a continuous feed, which holds the whole
+// document when the docs are included.
+const maxFeedLine = 64 * 1024 * 1024
+
+// streamFeed requests a feed and returns its body, which is closed as soon
+// as the context is done so that pending reads return.
+func (h handle) streamFeed(ctx context.Context, url string) (io.ReadCloser, error) {
+
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	res, err := h.newRequest(http.MethodGet, url).WithContext(ctx).Stream().Do()
+	if err != nil {
+		return nil, err
+	}
+
+	return closeOnDone(ctx, res), nil
+
+}
+
+func closeOnDone(ctx context.Context, res *http.Response) io.ReadCloser {
+
+	done := make(chan struct{})
+	go func() {
+		select {
+		case <-ctx.Done():
+			res.Body.Close()
+		case <-done:
+		}
+	}()
+
+	return &doneReadCloser{ReadCloser: res.Body, done: done}
+
+}
+
+type doneReadCloser struct {
+	io.ReadCloser
+	done chan struct{}
+}
+
+func (d *doneReadCloser) Close() error {
+	close(d.done)
+	return d.ReadCloser.Close()
+}
+
+func sendEvent[T any](ctx context.Context, events chan<- T, event T) bool {
+	select {
+	case events <- event:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
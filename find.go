@@ -0,0 +1,101 @@
+package couchcandy
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// FindQuery The body of a Mango query. Selector is required, the other
+// fields are optional.
+type FindQuery struct {
+	Selector       interface{}   `json:"selector"`
+	Fields         []string      `json:"fields,omitempty"`
+	Sort           []interface{} `json:"sort,omitempty"`
+	Limit          int           `json:"limit,omitempty"`
+	Skip           int           `json:"skip,omitempty"`
+	UseIndex       interface{}   `json:"use_index,omitempty"`
+	Bookmark       string        `json:"bookmark,omitempty"`
+	Conflicts      bool          `json:"conflicts,omitempty"`
+	ExecutionStats bool          `json:"execution_stats,omitempty"`
+}
+
+// FindResponse The response to a Mango query. Docs are json.RawMessage so
+// that they can be unmarshaled with the desired type, like ViewRow values.
+type FindResponse struct {
+	Docs           []json.RawMessage `json:"docs,omitempty"`
+	Bookmark       string            `json:"bookmark,omitempty"`
+	Warning        string            `json:"warning,omitempty"`
+	ExecutionStats *ExecutionStats   `json:"execution_stats,omitempty"`
+	Error          string            `json:"error,omitempty"`
+	Reason         string            `json:"reason,omitempty"`
+}
+
+// ExecutionStats The statistics returned by a Mango query when asked for.
+type ExecutionStats struct {
+	TotalKeysExamined       int     `json:"total_keys_examined"`
+	TotalDocsExamined       int     `json:"total_docs_examined"`
+	TotalQuorumDocsExamined int     `json:"total_quorum_docs_examined"`
+	ResultsReturned         int     `json:"results_returned"`
+	ExecutionTimeMs         float64 `json:"execution_time_ms"`
+}
+
+// ExplainResponse Describes the index and options a Mango query would use.
+type ExplainResponse struct {
+	DBName      string          `json:"dbname,omitempty"`
+	Index       json.RawMessage `json:"index,omitempty"`
+	Partitioned bool            `json:"partitioned,omitempty"`
+	Selector    json.RawMessage `json:"selector,omitempty"`
+	Opts        json.RawMessage `json:"opts,omitempty"`
+	Limit       int             `json:"limit,omitempty"`
+	Skip        int             `json:"skip,omitempty"`
+	Fields      json.RawMessage `json:"fields,omitempty"`
+	Range       json.RawMessage `json:"range,omitempty"`
+	Error       string          `json:"error,omitempty"`
+	Reason      string          `json:"reason,omitempty"`
+}
+
+// Find Runs the Mango query against the database.
+func (db *DB) Find(query FindQuery) (*FindResponse, error) {
+	return db.find(fmt.Sprintf("%s/_find", createDatabaseURL(db.session)), query)
+}
+
+// Explain Tells which index and options the Mango query would use.
+func (db *DB) Explain(query FindQuery) (*ExplainResponse, error) {
+	return db.explain(fmt.Sprintf("%s/_explain", createDatabaseURL(db.session)), query)
+}
+
+func (h handle) find(url string, query FindQuery) (*FindResponse, error) {
+
+	body, marshallError := json.Marshal(query)
+	if marshallError != nil {
+		return nil, marshallError
+	}
+
+	page, err := h.postJSON(url, string(body))
+	if err != nil {
+		return nil, err
+	}
+
+	response := &FindResponse{}
+	unmarshallError := json.Unmarshal(page, response)
+	return response, unmarshallError
+
+}
+
+func (h handle) explain(url string, query FindQuery) (*ExplainResponse, error) {
+
+	body, marshallError := json.Marshal(query)
+	if marshallError != nil {
+		return nil, marshallError
+	}
+
+	page, err := h.postJSON(url, string(body))
+	if err != nil {
+		return nil, err
+	}
+
+	response := &ExplainResponse{}
+	unmarshallError := json.Unmarshal(page, response)
+	return response, unmarshallError
+
+}
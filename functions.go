@@ -0,0 +1,74 @@
+package couchcandy
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+)
+
+const (
+	// HeaderUpdateNewRev is the header holding the revision written by an update function
+	HeaderUpdateNewRev string = "X-Couch-Update-NewRev"
+	// HeaderCouchID is the header holding the id of the document written by an update function
+	HeaderCouchID string = "X-Couch-Id"
+)
+
+// RawResponse The unparsed response of a show, list or update function, which
+// can return any content type.
+type RawResponse struct {
+	StatusCode  int
+	ContentType string
+	Header      http.Header
+	Body        []byte
+}
+
+// UpdateResponse The response of an update function. NewRev and DocID are
+// only set when the function wrote a document.
+type UpdateResponse struct {
+	RawResponse
+	NewRev string
+	DocID  string
+}
+
+// CallUpdateHandler Calls the update function fn of the design document ddoc.
+// The function receives the document with the passed id, or no document when
+// docID is empty, and the body as the request body.
+func (db *DB) CallUpdateHandler(ddoc, fn, docID, body string) (*UpdateResponse, error) {
+
+	functionURL := createDesignDocURL(db.session, ddoc, "_update", fn)
+
+	var raw *RawResponse
+	var err error
+	if docID == "" {
+		raw, err = db.newRequest(http.MethodPost, functionURL).JSON(body).Raw()
+	} else {
+		raw, err = db.newRequest(http.MethodPut, fmt.Sprintf("%s/%s", functionURL, escapeDocID(docID))).JSON(body).Raw()
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &UpdateResponse{
+		RawResponse: *raw,
+		NewRev:      raw.Header.Get(HeaderUpdateNewRev),
+		DocID:       raw.Header.Get(HeaderCouchID),
+	}, nil
+
+}
+
+// Show Calls the show function fn of the design document ddoc on the document
+// with the passed id, or on no document when docID is empty. The query is
+// passed to the function as the request query parameters.
+func (db *DB) Show(ddoc, fn, docID string, query url.Values) (*RawResponse, error) {
+
+	showURL := createDesignDocURL(db.session, ddoc, "_show", fn)
+	if docID != "" {
+		showURL = fmt.Sprintf("%s/%s", showURL, escapeDocID(docID))
+	}
+	if len(query) > 0 {
+		showURL = fmt.Sprintf("%s?%s", showURL, query.Encode())
+	}
+
+	return db.newRequest(http.MethodGet, showURL).Raw()
+
+}
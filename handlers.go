@@ -1,129 +1,141 @@
 package couchcandy
 
 import (
-	"bytes"
+	"context"
+	"encoding/json"
 	"io/ioutil"
 	"net/http"
-	"strings"
 )
 
-// defaultGet is default method with explicit "GET" and &http.Client{}
-func defaultGet(url string) (*http.Response, error) {
-	return defaultMethod(http.MethodGet, url, &http.Client{})
+// handle holds what the DB, Server and Node handles share : the client
+// whose middlewares send their requests, the copy of the session taken
+// when the handle was created and the context set by WithContext, if any.
+type handle struct {
+	client  *CouchCandy
+	session Session
+	ctx     context.Context
 }
 
-// defaultDelete is default method with explicit "DELETE" and &http.Client{}
-func defaultDelete(url string) (*http.Response, error) {
-	return defaultMethod(http.MethodDelete, url, &http.Client{})
-}
-
-// defaultMethod is for GET and DELETE statements
-func defaultMethod(method, url string, client CandyHTTPClient) (*http.Response, error) {
-
-	request, requestError := http.NewRequest(method, url, nil)
-	if requestError != nil {
-		return nil, requestError
-	}
-
-	response, err := client.Do(request)
-	if err != nil {
-		return nil, err
+// newRequest starts a request bound to the session and the context of the
+// handle rather than to the current session of the client.
+func (h handle) newRequest(method, url string) *Request {
+	request := h.client.NewRequest(method, url)
+	request.prefix = h.session.PathPrefix
+	request.timeout = h.session.Timeout
+	if h.ctx != nil {
+		request.ctx = h.ctx
 	}
-	return response, nil
-
+	return request
 }
 
-func defaultPostJSON(url, body string) (*http.Response, error) {
-	return defaultJSONWithBody(http.MethodPost, url, body, &http.Client{})
+// withContext returns a copy of the handle sending its requests with ctx.
+func (h handle) withContext(ctx context.Context) handle {
+	h.ctx = ctx
+	return h
 }
 
-func defaultPutJSON(url, body string) (*http.Response, error) {
-	return defaultJSONWithBody(http.MethodPut, url, body, &http.Client{})
+// get reads the whole response to a GET on url.
+func (h handle) get(url string) ([]byte, error) {
+	return h.newRequest(http.MethodGet, url).Read()
 }
 
-func defaultJSONWithBody(method, url, body string, client CandyHTTPClient) (*http.Response, error) {
+// delete reads the whole response to a DELETE on url.
+func (h handle) delete(url string) ([]byte, error) {
+	return h.newRequest(http.MethodDelete, url).Read()
+}
 
-	bodyJSON := strings.NewReader(body)
-	request, requestError := http.NewRequest(method, url, bodyJSON)
-	if requestError != nil {
-		return nil, requestError
-	}
+// postJSON reads the whole response to a POST of the JSON body on url.
+func (h handle) postJSON(url, body string) ([]byte, error) {
+	return h.newRequest(http.MethodPost, url).JSON(body).Read()
+}
 
-	request.Header.Add(HeaderContentType, JSONContentType)
-	response, err := client.Do(request)
-	if err != nil {
-		return nil, err
-	}
-	return response, nil
+// putJSON reads the whole response to a PUT of the JSON body on url.
+func (h handle) putJSON(url, body string) ([]byte, error) {
+	return h.newRequest(http.MethodPut, url).JSON(body).Read()
 }
 
-func defaultPutBytes(url, contentType string, body []byte) (*http.Response, error) {
-	return defaultBytesWithBody(http.MethodPut, url, contentType, body, &http.Client{})
+// putBytes reads the whole response to a PUT of the body on url.
+func (h handle) putBytes(url, contentType string, body []byte) ([]byte, error) {
+	return h.newRequest(http.MethodPut, url).Body(contentType, body).Read()
 }
 
-func defaultBytesWithBody(method, url, contentType string, body []byte, client CandyHTTPClient) (*http.Response, error) {
+// toRawResponse reads the whole response without interpreting it.
+func toRawResponse(res *http.Response, err error) (*RawResponse, error) {
 
-	request, err := http.NewRequest(method, url, bytes.NewReader(body))
 	if err != nil {
 		return nil, err
 	}
 
-	request.Header.Add(HeaderContentType, contentType)
-	response, err := client.Do(request)
+	body, err := ioutil.ReadAll(res.Body)
+	defer res.Body.Close()
 	if err != nil {
 		return nil, err
 	}
-	return response, nil
+
+	return &RawResponse{
+		StatusCode:  res.StatusCode,
+		ContentType: res.Header.Get(HeaderContentType),
+		Header:      res.Header,
+		Body:        body,
+	}, nil
 
 }
 
-func readBytesWithBody(url, contentType string, body []byte, handler func(string, string, []byte) (*http.Response, error)) ([]byte, error) {
+func (h handle) postOperation(url, body string) (*OperationResponse, error) {
 
-	res, err := handler(url, contentType, body)
+	page, err := h.postJSON(url, body)
 	if err != nil {
 		return nil, err
 	}
 
-	page, err := ioutil.ReadAll(res.Body)
-	defer res.Body.Close()
+	return toOperationResponse(page)
+
+}
+
+func (h handle) putOperation(url, body string) (*OperationResponse, error) {
+
+	page, err := h.putJSON(url, body)
 	if err != nil {
 		return nil, err
 	}
 
-	return page, nil
+	return toOperationResponse(page)
 
 }
 
-func readJSONWithBody(url, body string, handler func(str, bd string) (*http.Response, error)) ([]byte, error) {
+// getLimit reads the plain integer returned by the _revs_limit and
+// _purged_infos_limit endpoints, surfacing CouchDB errors when present.
+func (h handle) getLimit(url string) (int, error) {
 
-	res, err := handler(url, body)
+	page, err := h.get(url)
 	if err != nil {
-		return nil, err
+		return 0, err
 	}
 
-	page, err := ioutil.ReadAll(res.Body)
-	defer res.Body.Close()
-	if err != nil {
-		return nil, err
+	var limit int
+	if unmarshallError := json.Unmarshal(page, &limit); unmarshallError != nil {
+		if couchError := toCouchError(page); couchError != nil {
+			return 0, couchError
+		}
+		return 0, unmarshallError
 	}
 
-	return page, nil
+	return limit, nil
 
 }
 
-func readJSON(url string, handler func(str string) (*http.Response, error)) ([]byte, error) {
+// getJSON reads the url into v, turning CouchDB error objects into errors.
+func (h handle) getJSON(url string, v interface{}) error {
 
-	res, err := handler(url)
+	page, err := h.get(url)
 	if err != nil {
-		return nil, err
+		return err
 	}
 
-	page, err := ioutil.ReadAll(res.Body)
-	defer res.Body.Close()
-	if err != nil {
-		return nil, err
+	if couchError := toCouchError(page); couchError != nil {
+		return couchError
 	}
 
-	return page, nil
+	return json.Unmarshal(page, v)
 
 }
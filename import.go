@@ -0,0 +1,629 @@
+package couchcandy
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	// ImportCSV is the format of comma separated values with a header line
+	ImportCSV string = "csv"
+	// ImportNDJSON is the format of a JSON object per line
+	ImportNDJSON string = "ndjson"
+)
+
+const (
+	// FieldString converts the value to a string
+	FieldString string = "string"
+	// FieldNumber parses the value as a floating point number
+	FieldNumber string = "number"
+	// FieldInteger parses the value as an integer
+	FieldInteger string = "integer"
+	// FieldBoolean parses the value as a boolean, like "true", "false", "1" or "0"
+	FieldBoolean string = "boolean"
+	// FieldJSON parses the value as JSON, for arrays and objects held in a column
+	FieldJSON string = "json"
+)
+
+// DefaultImportWorkers is the number of batches written concurrently when none is given
+const DefaultImportWorkers int = 4
+
+// idPlaceholder matches the {column} placeholders of an id template.
+var idPlaceholder = regexp.MustCompile(`\{([^{}]+)\}`)
+
+// ImportMapping Tells how the rows of an import become documents. It can be
+// written in YAML or JSON and read with LoadImportMapping :
+//
+//	id: "user:{email}"
+//	constants:
+//	  type: user
+//	fields:
+//	  - column: email
+//	  - column: age
+//	    type: integer
+//	  - column: city
+//	    path: address.city
+//
+// ID : template of the document ids, {column} being replaced by the value of the column, generated by CouchDB when empty
+// Constants : fields set on every document, like its type
+// Fields : the columns to import, all of them as strings when empty
+type ImportMapping struct {
+	ID        string                 `json:"id,omitempty" yaml:"id,omitempty"`
+	Constants map[string]interface{} `json:"constants,omitempty" yaml:"constants,omitempty"`
+	Fields    []FieldMapping         `json:"fields,omitempty" yaml:"fields,omitempty"`
+}
+
+// FieldMapping Maps a column to a field of the documents. With NDJSON, the
+// column is the path of a field of the objects read.
+// Column : the name of the column, in the header of a CSV file
+// Path : where the value goes in the document, dot separated like "address.city", Column when empty
+// Type : one of the Field* types, the value being kept as read when empty, a string from a CSV file
+// OmitEmpty : leaves the field out when the column is empty, instead of setting an empty string or failing the conversion
+type FieldMapping struct {
+	Column    string `json:"column" yaml:"column"`
+	Path      string `json:"path,omitempty" yaml:"path,omitempty"`
+	Type      string `json:"type,omitempty" yaml:"type,omitempty"`
+	OmitEmpty bool   `json:"omit_empty,omitempty" yaml:"omit_empty,omitempty"`
+}
+
+// ImportOptions Options available when importing documents.
+// Format : ImportCSV or ImportNDJSON, required
+// Mapping : how the rows become documents
+// Comma : the separator of CSV files, a comma when 0
+// BatchSize : number of documents per _bulk_docs request, DefaultBatchSize when 0
+// Workers : number of batches written concurrently, DefaultImportWorkers when 0
+// Upsert : updates the documents that already exist, by fetching their current revision, instead of reporting a conflict
+type ImportOptions struct {
+	Format    string
+	Mapping   ImportMapping
+	Comma     rune
+	BatchSize int
+	Workers   int
+	Upsert    bool
+}
+
+// ImportResult The outcome of an import. Rows counts the rows read, Written
+// the documents written and Errors lists the rows that were not imported,
+// in their order.
+type ImportResult struct {
+	Rows    int           `json:"rows"`
+	Written int           `json:"written"`
+	Errors  []ImportError `json:"errors,omitempty"`
+}
+
+// ImportError A row that was not imported. Row is the number of the row,
+// starting at 1 with the first row after the header, and ID the id of its
+// document when known.
+type ImportError struct {
+	Row    int    `json:"row"`
+	ID     string `json:"id,omitempty"`
+	Reason string `json:"reason"`
+}
+
+func (e ImportError) Error() string {
+	if e.ID != "" {
+		return fmt.Sprintf("row %d (%s): %s", e.Row, e.ID, e.Reason)
+	}
+	return fmt.Sprintf("row %d: %s", e.Row, e.Reason)
+}
+
+// LoadImportMapping Reads a mapping from a YAML or JSON file.
+func LoadImportMapping(path string) (*ImportMapping, error) {
+
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	mapping := &ImportMapping{}
+	decoder := yaml.NewDecoder(strings.NewReader(string(content)))
+	decoder.KnownFields(true)
+	if err = decoder.Decode(mapping); err != nil && err != io.EOF {
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+	return mapping, nil
+
+}
+
+// importRow is a row read from the input, turned into a document.
+type importRow struct {
+	number   int
+	id       string
+	document map[string]interface{}
+}
+
+// Import Reads the rows of r, turns them into documents as mapped and
+// writes them in batches, several batches at a time. A row that cannot be
+// read, converted or written is reported in the result without stopping the
+// import, which only stops when the input is broken, like on an I/O error or
+// an unterminated quote, or when a request fails.
+func (db *DB) Import(r io.Reader, options ImportOptions) (*ImportResult, error) {
+
+	if err := options.Mapping.validate(); err != nil {
+		return nil, err
+	}
+
+	next, err := importReader(r, options)
+	if err != nil {
+		return nil, err
+	}
+
+	batchSize := options.BatchSize
+	if batchSize <= 0 {
+		batchSize = DefaultBatchSize
+	}
+	workers := options.Workers
+	if workers <= 0 {
+		workers = DefaultImportWorkers
+	}
+
+	result := &ImportResult{}
+	var lock sync.Mutex
+	var failure error
+	failed := func() bool {
+		lock.Lock()
+		defer lock.Unlock()
+		return failure != nil
+	}
+
+	batches := make(chan []importRow)
+	var wait sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wait.Add(1)
+		go func() {
+			defer wait.Done()
+			for batch := range batches {
+				if failed() {
+					continue
+				}
+				written, errs, err := db.importBatch(batch, options.Upsert)
+				lock.Lock()
+				result.Written += written
+				result.Errors = append(result.Errors, errs...)
+				if err != nil && failure == nil {
+					failure = err
+				}
+				lock.Unlock()
+			}
+		}()
+	}
+
+	// The rows read before the input fails are still written.
+	var readError error
+	batch := make([]importRow, 0, batchSize)
+	for !failed() {
+
+		source, err := next()
+		if err == io.EOF {
+			break
+		}
+		if reason, ok := err.(rowError); ok {
+			result.Rows++
+			lock.Lock()
+			result.Errors = append(result.Errors, ImportError{Row: result.Rows, Reason: string(reason)})
+			lock.Unlock()
+			continue
+		}
+		if err != nil {
+			readError = fmt.Errorf("row %d: %v", result.Rows+1, err)
+			break
+		}
+
+		result.Rows++
+		row, err := options.Mapping.document(result.Rows, source)
+		if err != nil {
+			lock.Lock()
+			result.Errors = append(result.Errors, ImportError{Row: result.Rows, ID: row.id, Reason: err.Error()})
+			lock.Unlock()
+			continue
+		}
+
+		batch = append(batch, row)
+		if len(batch) == batchSize {
+			batches <- batch
+			batch = make([]importRow, 0, batchSize)
+		}
+
+	}
+	if len(batch) != 0 && !failed() {
+		batches <- batch
+	}
+	close(batches)
+	wait.Wait()
+
+	sort.Slice(result.Errors, func(i, j int) bool { return result.Errors[i].Row < result.Errors[j].Row })
+	if failure != nil {
+		return result, failure
+	}
+	return result, readError
+
+}
+
+// importBatch writes the batch and returns the number of documents written
+// and the rows that failed.
+func (db *DB) importBatch(batch []importRow, upsert bool) (int, []ImportError, error) {
+
+	if upsert {
+		if err := db.fillRevisions(batch); err != nil {
+			return 0, nil, err
+		}
+	}
+
+	documents := make([]map[string]interface{}, len(batch))
+	for i, row := range batch {
+		documents[i] = row.document
+	}
+
+	responses, err := db.BulkDocs(documents, BulkDocsOptions{})
+	if err != nil {
+		return 0, nil, err
+	}
+
+	written := 0
+	errs := make([]ImportError, 0)
+	for i, response := range responses {
+		if i >= len(batch) {
+			break
+		}
+		if response.Error != "" {
+			errs = append(errs, ImportError{
+				Row:    batch[i].number,
+				ID:     response.ID,
+				Reason: fmt.Sprintf("%s: %s", response.Error, response.Reason),
+			})
+			continue
+		}
+		written++
+	}
+	return written, errs, nil
+
+}
+
+// fillRevisions sets the current revision of the documents of the batch
+// that already exist.
+func (db *DB) fillRevisions(batch []importRow) error {
+
+	ids := make([]string, 0, len(batch))
+	for _, row := range batch {
+		if row.id != "" {
+			ids = append(ids, row.id)
+		}
+	}
+	if len(ids) == 0 {
+		return nil
+	}
+
+	allDocuments, err := db.DocumentsByKeys(ids, Options{})
+	if err != nil {
+		return err
+	}
+	if allDocuments.Error != "" {
+		return fmt.Errorf("%s: %s", allDocuments.Error, allDocuments.Reason)
+	}
+
+	revisions := make(map[string]string, len(allDocuments.Rows))
+	for _, row := range allDocuments.Rows {
+		if row.Value.REV != "" {
+			revisions[row.ID] = row.Value.REV
+		}
+	}
+	for _, row := range batch {
+		if rev, ok := revisions[row.id]; ok {
+			row.document["_rev"] = rev
+		}
+	}
+	return nil
+
+}
+
+// rowError is a row of the input that cannot be read, which is reported
+// without stopping the import.
+type rowError string
+
+func (e rowError) Error() string {
+	return string(e)
+}
+
+// importReader returns a function reading the rows of r one after the
+// other, as the values of their columns, until io.EOF. The rows that cannot
+// be read are returned as a rowError, the reading going on with the next.
+// A quote left open at the end of a CSV input is not, since it swallowed
+// the rows that followed it : a quoting error followed by the end of the
+// input is returned as is.
+func importReader(r io.Reader, options ImportOptions) (func() (map[string]interface{}, error), error) {
+
+	switch options.Format {
+	case ImportCSV:
+
+		reader := csv.NewReader(r)
+		if options.Comma != 0 {
+			reader.Comma = options.Comma
+		}
+		reader.ReuseRecord = true
+		reader.FieldsPerRecord = -1
+		header, err := reader.Read()
+		if err != nil {
+			return nil, fmt.Errorf("csv header: %v", err)
+		}
+		header = append([]string(nil), header...)
+
+		// next holds the record read ahead after a quoting error.
+		var next []string
+		var nextError error
+		readAhead := false
+
+		return func() (map[string]interface{}, error) {
+
+			var record []string
+			if readAhead {
+				record, err, readAhead = next, nextError, false
+			} else {
+				record, err = reader.Read()
+			}
+
+			var parseError *csv.ParseError
+			if errors.As(err, &parseError) {
+				if errors.Is(parseError.Err, csv.ErrQuote) {
+					next, nextError = reader.Read()
+					if nextError == io.EOF {
+						return nil, err
+					}
+					next = append([]string(nil), next...)
+					readAhead = true
+				}
+				return nil, rowError(parseError.Error())
+			}
+			if err != nil {
+				return nil, err
+			}
+			if len(record) != len(header) {
+				return nil, rowError(fmt.Sprintf("expected %d columns as in the header, got %d", len(header), len(record)))
+			}
+			values := make(map[string]interface{}, len(header))
+			for i, column := range header {
+				values[column] = record[i]
+			}
+			return values, nil
+
+		}, nil
+
+	case ImportNDJSON:
+
+		reader := bufio.NewReader(r)
+		return func() (map[string]interface{}, error) {
+
+			line := []byte{}
+			for len(line) == 0 {
+				var err error
+				line, err = reader.ReadBytes('\n')
+				if err != nil && (err != io.EOF || len(line) == 0) {
+					return nil, err
+				}
+				line = bytes.TrimSpace(line)
+			}
+
+			values := make(map[string]interface{})
+			decoder := json.NewDecoder(bytes.NewReader(line))
+			decoder.UseNumber()
+			if err := decoder.Decode(&values); err != nil {
+				return nil, rowError(fmt.Sprintf("invalid JSON: %v", err))
+			}
+			if decoder.More() {
+				return nil, rowError("invalid JSON: several values on the line")
+			}
+			return values, nil
+
+		}, nil
+
+	default:
+		return nil, fmt.Errorf("unknown import format %q", options.Format)
+	}
+
+}
+
+// validate checks the types of the fields.
+func (m ImportMapping) validate() error {
+
+	for _, field := range m.Fields {
+		switch field.Type {
+		case "", FieldString, FieldNumber, FieldInteger, FieldBoolean, FieldJSON:
+		default:
+			return fmt.Errorf("column %q: unknown type %q", field.Column, field.Type)
+		}
+		if field.Column == "" {
+			return errors.New("mapping: a field has no column")
+		}
+	}
+	return nil
+
+}
+
+// document turns the values of a row into its document.
+func (m ImportMapping) document(number int, values map[string]interface{}) (importRow, error) {
+
+	row := importRow{number: number, document: make(map[string]interface{})}
+
+	if m.ID != "" {
+		id, err := m.expandID(values)
+		if err != nil {
+			return row, err
+		}
+		row.id = id
+	}
+
+	if len(m.Fields) == 0 {
+		for column, value := range values {
+			if err := setPath(row.document, column, value); err != nil {
+				return row, err
+			}
+		}
+	}
+
+	for _, field := range m.Fields {
+
+		value, ok := lookupPath(values, field.Column)
+		if !ok || value == nil || value == "" {
+			if field.OmitEmpty {
+				continue
+			}
+			if !ok {
+				return row, fmt.Errorf("missing column %q", field.Column)
+			}
+		}
+
+		converted, err := convertField(value, field.Type)
+		if err != nil {
+			return row, fmt.Errorf("column %q: %v", field.Column, err)
+		}
+
+		path := field.Path
+		if path == "" {
+			path = field.Column
+		}
+		if err = setPath(row.document, path, converted); err != nil {
+			return row, err
+		}
+
+	}
+
+	for path, value := range m.Constants {
+		if err := setPath(row.document, path, value); err != nil {
+			return row, err
+		}
+	}
+
+	if row.id != "" {
+		row.document["_id"] = row.id
+	} else if id, ok := row.document["_id"].(string); ok {
+		row.id = id
+	}
+	return row, nil
+
+}
+
+// expandID replaces the placeholders of the id template by the values of
+// their columns, which must not be empty.
+func (m ImportMapping) expandID(values map[string]interface{}) (string, error) {
+
+	var missing error
+	id := idPlaceholder.ReplaceAllStringFunc(m.ID, func(placeholder string) string {
+		column := strings.Trim(placeholder, "{}")
+		value, ok := lookupPath(values, column)
+		if !ok || value == nil || value == "" {
+			missing = fmt.Errorf("empty column %q in id template", column)
+			return ""
+		}
+		return fmt.Sprint(value)
+	})
+	return id, missing
+
+}
+
+// convertField converts a value read from a CSV column, always a string,
+// or a JSON field to the type of the field. Without a type, the value is
+// kept as it is read.
+func convertField(value interface{}, fieldType string) (interface{}, error) {
+
+	if fieldType == "" {
+		return value, nil
+	}
+
+	text, isText := value.(string)
+	if !isText {
+		switch typed := value.(type) {
+		case json.Number:
+			switch fieldType {
+			case FieldString:
+				return typed.String(), nil
+			case FieldNumber:
+				return typed.Float64()
+			case FieldInteger:
+				return typed.Int64()
+			}
+		case bool:
+			switch fieldType {
+			case FieldString:
+				return strconv.FormatBool(typed), nil
+			case FieldBoolean:
+				return typed, nil
+			}
+		}
+		if fieldType == FieldJSON {
+			return value, nil
+		}
+		return nil, fmt.Errorf("%v is not a %s", value, fieldType)
+	}
+
+	switch fieldType {
+	case FieldNumber:
+		return strconv.ParseFloat(strings.TrimSpace(text), 64)
+	case FieldInteger:
+		return strconv.ParseInt(strings.TrimSpace(text), 10, 64)
+	case FieldBoolean:
+		return strconv.ParseBool(strings.TrimSpace(text))
+	case FieldJSON:
+		var parsed interface{}
+		err := json.Unmarshal([]byte(text), &parsed)
+		return parsed, err
+	}
+	return text, nil
+
+}
+
+// lookupPath returns the value at the dot separated path, the column itself
+// when it holds a dot.
+func lookupPath(values map[string]interface{}, path string) (interface{}, bool) {
+
+	if value, ok := values[path]; ok {
+		return value, true
+	}
+
+	current := interface{}(values)
+	for _, key := range strings.Split(path, ".") {
+		object, ok := current.(map[string]interface{})
+		if !ok {
+			return nil, false
+		}
+		if current, ok = object[key]; !ok {
+			return nil, false
+		}
+	}
+	return current, true
+
+}
+
+// setPath sets the value at the dot separated path of the document,
+// creating the intermediate objects.
+func setPath(document map[string]interface{}, path string, value interface{}) error {
+
+	keys := strings.Split(path, ".")
+	current := document
+	for _, key := range keys[:len(keys)-1] {
+		next, ok := current[key]
+		if !ok {
+			child := make(map[string]interface{})
+			current[key] = child
+			current = child
+			continue
+		}
+		if current, ok = next.(map[string]interface{}); !ok {
+			return errors.New("path " + path + " goes through a field that is not an object")
+		}
+	}
+	current[keys[len(keys)-1]] = value
+	return nil
+
+}
@@ -0,0 +1,235 @@
+package couchcandy
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+	"testing"
+	"testing/iotest"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// importServer records the documents written through _bulk_docs, rejecting
+// the ids in conflicts, and answers _all_docs with the revisions.
+func importServer(t *testing.T, couchcandy *CouchCandy, conflicts map[string]bool, revisions map[string]string) (*router, *[]map[string]interface{}) {
+
+	written := make([]map[string]interface{}, 0)
+	server := route(couchcandy).
+		Handle(http.MethodPost, "/lendr/_all_docs", func(req *http.Request, body []byte) (*http.Response, error) {
+			keys := &AllDocumentsKeys{}
+			assert.Nil(t, json.Unmarshal(body, keys))
+			rows := make([]string, 0)
+			for _, key := range keys.Keys {
+				if rev, ok := revisions[key]; ok {
+					rows = append(rows, `{"id":"`+key+`","key":"`+key+`","value":{"rev":"`+rev+`"}}`)
+				} else {
+					rows = append(rows, `{"key":"`+key+`","error":"not_found"}`)
+				}
+			}
+			return statusResponse(http.StatusOK, `{"rows":[`+strings.Join(rows, ",")+`]}`), nil
+		}).
+		Handle(http.MethodPost, "/lendr/_bulk_docs", func(req *http.Request, body []byte) (*http.Response, error) {
+			request := &struct {
+				Docs []map[string]interface{} `json:"docs"`
+			}{}
+			assert.Nil(t, json.Unmarshal(body, request))
+			responses := make([]string, len(request.Docs))
+			for i, doc := range request.Docs {
+				id, _ := doc["_id"].(string)
+				if conflicts[id] {
+					responses[i] = `{"id":"` + id + `","error":"conflict","reason":"Document update conflict."}`
+					continue
+				}
+				written = append(written, doc)
+				responses[i] = `{"ok":true,"id":"` + id + `","rev":"1-abc"}`
+			}
+			return statusResponse(http.StatusCreated, `[`+strings.Join(responses, ",")+`]`), nil
+		})
+	return server, &written
+
+}
+
+func TestImportCSV(t *testing.T) {
+
+	couchcandy := newTestClient()
+	_, written := importServer(t, couchcandy, map[string]bool{"user:joe@example.com": true}, nil)
+
+	input := `email,age,city,admin,tags
+ann@example.com,34,Montréal,true,"[""a"",""b""]"
+bob@example.com,old,Paris,false,[]
+,20,Lyon,false,[]
+joe@example.com,51,,1,[]
+kim@example.com,27,Québec,0,
+`
+	result, err := couchcandy.DB("lendr").Import(strings.NewReader(input), ImportOptions{
+		Format: ImportCSV,
+		Mapping: ImportMapping{
+			ID:        "user:{email}",
+			Constants: map[string]interface{}{"type": "user"},
+			Fields: []FieldMapping{
+				{Column: "email"},
+				{Column: "age", Type: FieldInteger},
+				{Column: "city", Path: "address.city", OmitEmpty: true},
+				{Column: "admin", Type: FieldBoolean},
+				{Column: "tags", Type: FieldJSON, OmitEmpty: true},
+			},
+		},
+		BatchSize: 2,
+		Workers:   2,
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, 5, result.Rows)
+	assert.Equal(t, 2, result.Written)
+	assert.Equal(t, []ImportError{
+		{Row: 2, ID: "user:bob@example.com", Reason: `column "age": strconv.ParseInt: parsing "old": invalid syntax`},
+		{Row: 3, Reason: `empty column "email" in id template`},
+		{Row: 4, ID: "user:joe@example.com", Reason: "conflict: Document update conflict."},
+	}, result.Errors)
+	assert.Equal(t, "row 3: empty column \"email\" in id template", result.Errors[1].Error())
+
+	sort.Slice(*written, func(i, j int) bool { return (*written)[i]["_id"].(string) < (*written)[j]["_id"].(string) })
+	documents, _ := json.Marshal(*written)
+	assert.JSONEq(t, `[
+		{"_id":"user:ann@example.com","type":"user","email":"ann@example.com","age":34,"address":{"city":"Montréal"},"admin":true,"tags":["a","b"]},
+		{"_id":"user:kim@example.com","type":"user","email":"kim@example.com","age":27,"address":{"city":"Québec"},"admin":false}
+	]`, string(documents))
+
+}
+
+func TestImportNDJSONUpsert(t *testing.T) {
+
+	couchcandy := newTestClient()
+	_, written := importServer(t, couchcandy, nil, map[string]string{"card-1": "3-c"})
+
+	input := `{"_id":"card-1","suit":"hearts","rank":{"value":1}}
+{"_id":"card-2","suit":"spades","rank":{"value":"2"}}
+`
+	result, err := couchcandy.DB("lendr").Import(strings.NewReader(input), ImportOptions{
+		Format: ImportNDJSON,
+		Mapping: ImportMapping{
+			Fields: []FieldMapping{
+				{Column: "_id"},
+				{Column: "suit"},
+				{Column: "rank.value", Path: "rank", Type: FieldInteger},
+			},
+		},
+		Upsert: true,
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, &ImportResult{Rows: 2, Written: 2}, result)
+
+	documents, _ := json.Marshal(*written)
+	assert.JSONEq(t, `[
+		{"_id":"card-1","_rev":"3-c","suit":"hearts","rank":1},
+		{"_id":"card-2","suit":"spades","rank":2}
+	]`, string(documents))
+
+	*written = (*written)[:0]
+	result, err = couchcandy.DB("lendr").Import(strings.NewReader(input), ImportOptions{Format: ImportNDJSON})
+	assert.Nil(t, err)
+	assert.Equal(t, 2, result.Written)
+	documents, _ = json.Marshal(*written)
+	assert.JSONEq(t, `[
+		{"_id":"card-1","suit":"hearts","rank":{"value":1}},
+		{"_id":"card-2","suit":"spades","rank":{"value":"2"}}
+	]`, string(documents))
+
+}
+
+func TestImportErrors(t *testing.T) {
+
+	couchcandy := newTestClient()
+	importServer(t, couchcandy, nil, nil)
+	db := couchcandy.DB("lendr")
+
+	_, err := db.Import(strings.NewReader(""), ImportOptions{Format: "xml"})
+	assert.EqualError(t, err, `unknown import format "xml"`)
+
+	_, err = db.Import(strings.NewReader("a\n1\n"), ImportOptions{Format: ImportCSV, Mapping: ImportMapping{Fields: []FieldMapping{{Column: "a", Type: "date"}}}})
+	assert.EqualError(t, err, `column "a": unknown type "date"`)
+
+	result, err := db.Import(strings.NewReader("{\"a\":1}\n\n{\"a\":\n[1]\n{} {}\n{\"a\":2}"), ImportOptions{Format: ImportNDJSON})
+	assert.Nil(t, err)
+	assert.Equal(t, 5, result.Rows)
+	assert.Equal(t, 2, result.Written)
+	assert.Equal(t, []ImportError{
+		{Row: 2, Reason: "invalid JSON: unexpected EOF"},
+		{Row: 3, Reason: "invalid JSON: json: cannot unmarshal array into Go value of type map[string]interface {}"},
+		{Row: 4, Reason: "invalid JSON: several values on the line"},
+	}, result.Errors)
+
+	result, err = db.Import(strings.NewReader("a,b\n1,2\n3\n4,5,6\n7,8\n"), ImportOptions{Format: ImportCSV})
+	assert.Nil(t, err)
+	assert.Equal(t, 4, result.Rows)
+	assert.Equal(t, 2, result.Written)
+	assert.Equal(t, []ImportError{
+		{Row: 2, Reason: "expected 2 columns as in the header, got 1"},
+		{Row: 3, Reason: "expected 2 columns as in the header, got 3"},
+	}, result.Errors)
+
+	result, err = db.Import(strings.NewReader("a,b\n1,2\nx\"y,3\n\"4\"5,6\n7,8\n"), ImportOptions{Format: ImportCSV})
+	assert.Nil(t, err)
+	assert.Equal(t, 4, result.Rows)
+	assert.Equal(t, 2, result.Written)
+	assert.Equal(t, []ImportError{
+		{Row: 2, Reason: `parse error on line 3, column 2: bare " in non-quoted-field`},
+		{Row: 3, Reason: `parse error on line 4, column 3: extraneous or missing " in quoted-field`},
+	}, result.Errors)
+
+	result, err = db.Import(strings.NewReader("a,b\n1,2\n\"3,4\n5,6\n"), ImportOptions{Format: ImportCSV})
+	assert.EqualError(t, err, `row 2: record on line 3; parse error on line 4, column 5: extraneous or missing " in quoted-field`)
+	assert.Equal(t, 1, result.Written)
+
+	result, err = db.Import(io.MultiReader(strings.NewReader("{\"a\":1}\n"), iotest.ErrReader(errors.New("disk failure"))), ImportOptions{Format: ImportNDJSON})
+	assert.EqualError(t, err, "row 2: disk failure")
+	assert.Equal(t, 1, result.Written)
+
+}
+
+func TestImportStopsAfterFailure(t *testing.T) {
+
+	couchcandy := newTestClient()
+	server := route(couchcandy).
+		Reply(http.MethodPost, "/lendr/_bulk_docs", http.StatusInternalServerError, `{"error":"unknown_error","reason":"function_clause"}`)
+
+	_, err := couchcandy.DB("lendr").Import(strings.NewReader("a\n1\n2\n3\n4\n"), ImportOptions{Format: ImportCSV, BatchSize: 1, Workers: 1})
+	assert.NotNil(t, err)
+	assert.Equal(t, []string{"POST /lendr/_bulk_docs?"}, server.Requests())
+
+}
+
+func TestLoadImportMapping(t *testing.T) {
+
+	path := writeFile(t, "users.yaml", `
+id: "user:{email}"
+constants:
+  type: user
+fields:
+  - column: email
+  - column: age
+    type: integer
+  - column: city
+    path: address.city
+    omit_empty: true
+`)
+
+	mapping, err := LoadImportMapping(path)
+	assert.Nil(t, err)
+	assert.Equal(t, &ImportMapping{
+		ID:        "user:{email}",
+		Constants: map[string]interface{}{"type": "user"},
+		Fields: []FieldMapping{
+			{Column: "email"},
+			{Column: "age", Type: FieldInteger},
+			{Column: "city", Path: "address.city", OmitEmpty: true},
+		},
+	}, mapping)
+
+	_, err = LoadImportMapping(writeFile(t, "users.json", `{"id": "user:{email}", "colums": []}`))
+	assert.NotNil(t, err)
+
+}
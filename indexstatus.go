@@ -0,0 +1,174 @@
+package couchcandy
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// indexPollInterval is the time waited between two checks of the indexers
+var indexPollInterval = time.Second
+
+// DesignDocInfo The information about the view index of a design document.
+type DesignDocInfo struct {
+	Name      string    `json:"name,omitempty"`
+	ViewIndex ViewIndex `json:"view_index,omitempty"`
+	Error     string    `json:"error,omitempty"`
+	Reason    string    `json:"reason,omitempty"`
+}
+
+// ViewIndex The state of the view index of a design document.
+type ViewIndex struct {
+	Signature      string          `json:"signature,omitempty"`
+	Language       string          `json:"language,omitempty"`
+	Sizes          IndexSizes      `json:"sizes,omitempty"`
+	UpdateSeq      json.RawMessage `json:"update_seq,omitempty"`
+	PurgeSeq       json.RawMessage `json:"purge_seq,omitempty"`
+	UpdaterRunning bool            `json:"updater_running,omitempty"`
+	CompactRunning bool            `json:"compact_running,omitempty"`
+	WaitingClients int             `json:"waiting_clients,omitempty"`
+	WaitingCommit  bool            `json:"waiting_commit,omitempty"`
+}
+
+// IndexSizes The sizes of a view index, in bytes.
+type IndexSizes struct {
+	Active   int64 `json:"active,omitempty"`
+	External int64 `json:"external,omitempty"`
+	File     int64 `json:"file,omitempty"`
+}
+
+// DesignDocument Returns the named design document. The ddoc is the design
+// document name without the "_design/" prefix.
+func (db *DB) DesignDocument(ddoc string) (*DesignDocument, error) {
+
+	page, err := db.get(createDesignDocURL(db.session, ddoc))
+	if err != nil {
+		return nil, err
+	}
+
+	if couchError := toCouchError(page); couchError != nil {
+		return nil, couchError
+	}
+
+	designDoc := &DesignDocument{}
+	unmarshallError := json.Unmarshal(page, designDoc)
+	return designDoc, unmarshallError
+
+}
+
+// DesignDocInfo Returns the state of the view index of the named design document.
+func (db *DB) DesignDocInfo(ddoc string) (*DesignDocInfo, error) {
+
+	info := &DesignDocInfo{}
+	err := db.getJSON(createDesignDocURL(db.session, ddoc, "_info"), info)
+	return info, err
+
+}
+
+// WaitForIndex Triggers the build of the view index of the named design
+// document and blocks until it is up to date, or until the context is done.
+// The progress is followed through the indexer tasks of the server rather
+// than by holding a view request open for the whole build : the index is
+// built once no indexer task runs for the design document and its updater
+// is not running either. All the requests are sent with the context.
+func (db *DB) WaitForIndex(ctx context.Context, ddoc string) error {
+
+	db = db.WithContext(ctx)
+	ddoc = strings.TrimPrefix(ddoc, designPrefix)
+
+	designDoc, err := db.DesignDocument(ddoc)
+	if err != nil {
+		return err
+	}
+
+	view := ""
+	for name, definition := range designDoc.Views {
+		if definition.HasMap() {
+			view = name
+			break
+		}
+	}
+	if view == "" || designDoc.IsIndex() {
+		return fmt.Errorf("%s%s has no view to index", designPrefix, ddoc)
+	}
+
+	// update=lazy returns right away and starts the indexer in the background.
+	viewURL := fmt.Sprintf("%s?limit=0", createDesignDocURL(db.session, ddoc, "_view", view))
+	if err = db.queryView(viewURL + "&update=lazy"); err != nil {
+		return err
+	}
+
+	for {
+
+		indexing, err := db.indexing(ddoc)
+		if err != nil {
+			return err
+		}
+
+		if !indexing {
+			// The index is idle, this last query returns as soon as it
+			// caught up with the changes made since the indexer stopped.
+			return db.queryView(viewURL)
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(indexPollInterval):
+		}
+
+	}
+
+}
+
+// indexing tells if an indexer task is running for the design document, or
+// if its updater is.
+func (db *DB) indexing(ddoc string) (bool, error) {
+
+	server := &Server{db.handle}
+	tasks, err := server.ActiveTasks()
+	if err != nil {
+		return false, err
+	}
+
+	for _, task := range tasks {
+		if task.Type == TaskIndexer && task.DesignDocument == designPrefix+ddoc && isShardOf(task.Database, db.session.Database) {
+			return true, nil
+		}
+	}
+
+	info, err := db.DesignDocInfo(ddoc)
+	if err != nil {
+		return false, err
+	}
+	return info.ViewIndex.UpdaterRunning, nil
+
+}
+
+func (db *DB) queryView(url string) error {
+
+	page, err := db.get(url)
+	if err != nil {
+		return err
+	}
+
+	return toCouchError(page)
+
+}
+
+// isShardOf tells if the database of a task is the named database or one of
+// its shards, named like "shards/00000000-7fffffff/name.1692345678".
+func isShardOf(database, name string) bool {
+	if database == name {
+		return true
+	}
+	if !strings.HasPrefix(database, "shards/") {
+		return false
+	}
+	shard := strings.TrimPrefix(database, "shards/")
+	shard = shard[strings.Index(shard, "/")+1:]
+	dot := strings.LastIndex(shard, ".")
+	return dot > 0 && shard[:dot] == name
+}
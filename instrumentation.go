@@ -0,0 +1,161 @@
+package couchcandy
+
+import (
+	"context"
+	"net/http"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// HeaderTraceParent is the W3C trace context header
+const HeaderTraceParent string = "traceparent"
+
+// Endpoint The class of CouchDB endpoint a request is sent to, used to
+// group the requests in metrics and traces.
+type Endpoint string
+
+const (
+	// EndpointDocRead Reading documents : GET and HEAD of a document, _all_docs, _bulk_get...
+	EndpointDocRead Endpoint = "doc_read"
+	// EndpointDocWrite Writing documents : PUT, POST, DELETE and COPY of a document, _bulk_docs...
+	EndpointDocWrite Endpoint = "doc_write"
+	// EndpointView Querying views, list and show functions
+	EndpointView Endpoint = "view"
+	// EndpointFind Mango queries and indexes
+	EndpointFind Endpoint = "find"
+	// EndpointChanges The _changes and _db_updates feeds
+	EndpointChanges Endpoint = "changes"
+	// EndpointDatabase Database level calls : info, creation, compaction, security...
+	EndpointDatabase Endpoint = "database"
+	// EndpointServer Server level calls : _all_dbs, _up, _node...
+	EndpointServer Endpoint = "server"
+)
+
+// Instrumentation Hooks called around every request of the client, to
+// measure or trace them. OnRequestStart can return a modified request, to
+// carry a span in its context or inject propagation headers, or nil to
+// leave the request as is. OnRequestDone receives the response, or the
+// error, once the response headers are received.
+type Instrumentation interface {
+	OnRequestStart(req *http.Request, endpoint Endpoint) *http.Request
+	OnRequestDone(req *http.Request, endpoint Endpoint, res *http.Response, err error, duration time.Duration)
+}
+
+// Instrument Calls the instrumentation around the requests going through
+// it. The client adds it next to the transport when its Instrumentation is
+// set, use it directly to place it elsewhere in the middlewares.
+func Instrument(instrumentation Instrumentation) Middleware {
+	return func(next CandyHTTPClient) CandyHTTPClient {
+		return TransportFunc(func(req *http.Request) (*http.Response, error) {
+
+			prefix, _ := req.Context().Value(pathPrefixKey{}).(string)
+			endpoint := classify(req, prefix)
+			if started := instrumentation.OnRequestStart(req, endpoint); started != nil {
+				req = started
+			}
+
+			start := time.Now()
+			res, err := next.Do(req)
+			instrumentation.OnRequestDone(req, endpoint, res, err, time.Since(start))
+			return res, err
+
+		})
+	}
+}
+
+// systemDatabases are the databases whose name starts with an underscore
+var systemDatabases = map[string]bool{
+	UsersDatabase:     true,
+	"_replicator":     true,
+	"_global_changes": true,
+}
+
+// pathPrefixKey holds the path prefix of the Request in the context of the
+// http request, so that Instrument classifies it relatively to it.
+type pathPrefixKey struct{}
+
+// classify tells the endpoint class of the request from its method and its
+// path relative to the prefix.
+func classify(req *http.Request, prefix string) Endpoint {
+
+	path := strings.Trim(req.URL.Path, "/")
+	if prefix != "" {
+		path = strings.Trim(strings.TrimPrefix(path, strings.Trim(prefix, "/")), "/")
+	}
+
+	segments := strings.Split(path, "/")
+	if segments[0] == "" {
+		return EndpointServer
+	}
+	if segments[0] == "_db_updates" {
+		return EndpointChanges
+	}
+	if strings.HasPrefix(segments[0], "_") && !systemDatabases[segments[0]] {
+		return EndpointServer
+	}
+
+	for _, segment := range segments[1:] {
+		switch segment {
+		case "_view", "_list", "_show":
+			return EndpointView
+		case "_find", "_explain", "_index":
+			return EndpointFind
+		case "_changes":
+			return EndpointChanges
+		case "_all_docs", "_local_docs", "_design_docs", "_bulk_get":
+			return EndpointDocRead
+		case "_bulk_docs", "_update":
+			return EndpointDocWrite
+		}
+	}
+
+	if len(segments) == 1 || segments[1] == "_partition" || segments[len(segments)-1] == "_info" {
+		return EndpointDatabase
+	}
+	if strings.HasPrefix(segments[1], "_") && segments[1] != "_design" && segments[1] != "_local" {
+		return EndpointDatabase
+	}
+
+	if req.Method == http.MethodGet || req.Method == http.MethodHead {
+		return EndpointDocRead
+	}
+	return EndpointDocWrite
+
+}
+
+type traceParentKey struct{}
+
+var traceParentPattern = regexp.MustCompile(`^[0-9a-f]{2}-[0-9a-f]{32}-[0-9a-f]{16}-[0-9a-f]{2}$`)
+
+// ContextWithTraceParent Returns a context carrying the W3C traceparent of
+// the current span. The requests sent with this context propagate it to
+// CouchDB in the traceparent header, so that they appear in the distributed
+// trace. An invalid traceparent is ignored.
+func ContextWithTraceParent(ctx context.Context, traceParent string) context.Context {
+	if !traceParentPattern.MatchString(traceParent) {
+		return ctx
+	}
+	return context.WithValue(ctx, traceParentKey{}, traceParent)
+}
+
+// TraceParentFromContext Returns the traceparent carried by the context, if any.
+func TraceParentFromContext(ctx context.Context) (string, bool) {
+	traceParent, ok := ctx.Value(traceParentKey{}).(string)
+	return traceParent, ok
+}
+
+// propagateTraceParent sets the traceparent header from the context of the
+// request, unless the request already has one.
+func propagateTraceParent(next CandyHTTPClient) CandyHTTPClient {
+	return TransportFunc(func(req *http.Request) (*http.Response, error) {
+
+		traceParent, ok := TraceParentFromContext(req.Context())
+		if ok && req.Header.Get(HeaderTraceParent) == "" {
+			req = req.Clone(req.Context())
+			req.Header.Set(HeaderTraceParent, traceParent)
+		}
+		return next.Do(req)
+
+	})
+}
@@ -0,0 +1,227 @@
+package couchcandy
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestClassify(t *testing.T) {
+
+	cases := []struct {
+		method   string
+		path     string
+		expected Endpoint
+	}{
+		{http.MethodGet, "/", EndpointServer},
+		{http.MethodGet, "/_all_dbs", EndpointServer},
+		{http.MethodGet, "/_node/_local/_config", EndpointServer},
+		{http.MethodGet, "/_db_updates", EndpointChanges},
+		{http.MethodGet, "/lendr", EndpointDatabase},
+		{http.MethodPut, "/lendr", EndpointDatabase},
+		{http.MethodPost, "/lendr/_compact", EndpointDatabase},
+		{http.MethodGet, "/lendr/_security", EndpointDatabase},
+		{http.MethodGet, "/lendr/_design/cards/_info", EndpointDatabase},
+		{http.MethodGet, "/lendr/_partition/sensor", EndpointDatabase},
+		{http.MethodGet, "/_users", EndpointDatabase},
+		{http.MethodGet, "/_users/org.couchdb.user:jan", EndpointDocRead},
+		{http.MethodGet, "/lendr/card-1", EndpointDocRead},
+		{http.MethodHead, "/lendr/card-1", EndpointDocRead},
+		{http.MethodGet, "/lendr/_local/checkpoint", EndpointDocRead},
+		{http.MethodGet, "/lendr/_design/cards", EndpointDocRead},
+		{http.MethodPost, "/lendr/_all_docs", EndpointDocRead},
+		{http.MethodGet, "/lendr/_partition/sensor/_all_docs", EndpointDocRead},
+		{http.MethodPut, "/lendr/card-1", EndpointDocWrite},
+		{http.MethodPost, "/lendr", EndpointDatabase},
+		{http.MethodDelete, "/lendr/card-1", EndpointDocWrite},
+		{MethodCopy, "/lendr/card-1", EndpointDocWrite},
+		{http.MethodPut, "/lendr/card-1/picture.png", EndpointDocWrite},
+		{http.MethodPost, "/lendr/_bulk_docs", EndpointDocWrite},
+		{http.MethodPost, "/lendr/_design/cards/_update/stamp", EndpointDocWrite},
+		{http.MethodGet, "/lendr/_design/cards/_view/by_suit", EndpointView},
+		{http.MethodGet, "/lendr/_design/cards/_list/table/by_suit", EndpointView},
+		{http.MethodGet, "/lendr/_design/cards/_show/card/card-1", EndpointView},
+		{http.MethodPost, "/lendr/_find", EndpointFind},
+		{http.MethodPost, "/lendr/_partition/sensor/_explain", EndpointFind},
+		{http.MethodGet, "/lendr/_changes", EndpointChanges},
+	}
+
+	for _, c := range cases {
+		req, err := http.NewRequest(c.method, "http://127.0.0.1:5984"+c.path, nil)
+		assert.Nil(t, err)
+		assert.Equal(t, c.expected, classify(req, ""), "%s %s", c.method, c.path)
+	}
+
+	req, err := http.NewRequest(http.MethodGet, "http://proxy/couch/lendr/card-1", nil)
+	assert.Nil(t, err)
+	assert.Equal(t, EndpointDocRead, classify(req, "/couch/"))
+
+}
+
+type recordingInstrumentation struct {
+	started  []Endpoint
+	done     []Endpoint
+	errors   []error
+	received *http.Request
+}
+
+type spanKey struct{}
+
+func (r *recordingInstrumentation) OnRequestStart(req *http.Request, endpoint Endpoint) *http.Request {
+	r.started = append(r.started, endpoint)
+	return req.WithContext(context.WithValue(req.Context(), spanKey{}, "span"))
+}
+
+func (r *recordingInstrumentation) OnRequestDone(req *http.Request, endpoint Endpoint, res *http.Response, err error, duration time.Duration) {
+	r.done = append(r.done, endpoint)
+	r.errors = append(r.errors, err)
+	r.received = req
+}
+
+func TestInstrumentation(t *testing.T) {
+
+	couchcandy := NewCouchCandy(Session{
+		Host: "http://127.0.0.1", Port: 5984, Database: "lendr", Username: "test", Password: "gotest",
+	})
+	var span interface{}
+	couchcandy.Transport = TransportFunc(func(req *http.Request) (*http.Response, error) {
+		span = req.Context().Value(spanKey{})
+		if req.Method == http.MethodPost {
+			return nil, fmt.Errorf("Deliberate error from TestInstrumentation()")
+		}
+		return statusResponse(http.StatusOK, `{"rows":[]}`), nil
+	})
+	instrumentation := &recordingInstrumentation{}
+	couchcandy.Instrumentation = instrumentation
+
+	_, err := couchcandy.View("cards", "by_suit", Options{})
+	assert.Nil(t, err)
+	assert.Equal(t, "span", span)
+
+	_, err = couchcandy.DB("lendr").Find(FindQuery{Selector: map[string]interface{}{"suit": "spades"}})
+	assert.NotNil(t, err)
+
+	assert.Equal(t, []Endpoint{EndpointView, EndpointFind}, instrumentation.started)
+	assert.Equal(t, []Endpoint{EndpointView, EndpointFind}, instrumentation.done)
+	assert.Nil(t, instrumentation.errors[0])
+	assert.NotNil(t, instrumentation.errors[1])
+	assert.Equal(t, "span", instrumentation.received.Context().Value(spanKey{}))
+
+}
+
+func TestInstrumentationHandleSession(t *testing.T) {
+
+	couchcandy := NewCouchCandy(Session{
+		Host: "http://proxy", Port: 80, PathPrefix: "couch", Database: "lendr", Timeout: time.Minute,
+	})
+	var deadline bool
+	couchcandy.Transport = TransportFunc(func(req *http.Request) (*http.Response, error) {
+		_, deadline = req.Context().Deadline()
+		return statusResponse(http.StatusOK, `{}`), nil
+	})
+	instrumentation := &recordingInstrumentation{}
+	couchcandy.Instrumentation = instrumentation
+
+	db := couchcandy.DB("lendr")
+	couchcandy.Session = Session{Host: "http://127.0.0.1", Port: 5984}
+
+	err := db.Document("card-1", &map[string]interface{}{}, Options{})
+	assert.Nil(t, err)
+	assert.Equal(t, []Endpoint{EndpointDocRead}, instrumentation.started)
+	assert.True(t, deadline)
+
+}
+
+func TestTraceParentPropagation(t *testing.T) {
+
+	couchcandy := NewCouchCandy(Session{
+		Host: "http://127.0.0.1", Port: 5984, Database: "lendr", Username: "test", Password: "gotest",
+	})
+	var traceParent string
+	couchcandy.Transport = TransportFunc(func(req *http.Request) (*http.Response, error) {
+		traceParent = req.Header.Get(HeaderTraceParent)
+		return statusResponse(http.StatusOK, `{}`), nil
+	})
+
+	const parent = "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"
+	ctx := ContextWithTraceParent(context.Background(), parent)
+	_, err := couchcandy.NewRequest(http.MethodGet, "http://127.0.0.1:5984/lendr").WithContext(ctx).Do()
+	assert.Nil(t, err)
+	assert.Equal(t, parent, traceParent)
+
+	_, err = couchcandy.NewRequest(http.MethodGet, "http://127.0.0.1:5984/lendr").Do()
+	assert.Nil(t, err)
+	assert.Equal(t, "", traceParent)
+
+	db := couchcandy.DB("lendr")
+	err = db.WithContext(ctx).Document("card-1", &map[string]interface{}{}, Options{})
+	assert.Nil(t, err)
+	assert.Equal(t, parent, traceParent)
+
+	err = db.Document("card-1", &map[string]interface{}{}, Options{})
+	assert.Nil(t, err)
+	assert.Equal(t, "", traceParent)
+
+	_, err = couchcandy.Server().WithContext(ctx).Up()
+	assert.Nil(t, err)
+	assert.Equal(t, parent, traceParent)
+
+	_, found := TraceParentFromContext(ContextWithTraceParent(context.Background(), "not-a-traceparent"))
+	assert.False(t, found)
+
+}
+
+func TestMetrics(t *testing.T) {
+
+	metrics := NewMetrics(0.1, 1)
+	get, _ := http.NewRequest(http.MethodGet, "http://127.0.0.1:5984/lendr/card-1", nil)
+
+	metrics.OnRequestDone(get, EndpointDocRead, &http.Response{StatusCode: http.StatusOK}, nil, 50*time.Millisecond)
+	metrics.OnRequestDone(get, EndpointDocRead, &http.Response{StatusCode: http.StatusNotFound}, nil, 500*time.Millisecond)
+	metrics.OnRequestDone(get, EndpointDocRead, nil, fmt.Errorf("Deliberate error from TestMetrics()"), 2*time.Second)
+
+	assert.Equal(t, uint64(1), metrics.Requests(EndpointDocRead, http.MethodGet, "200"))
+	assert.Equal(t, uint64(1), metrics.Requests(EndpointDocRead, http.MethodGet, "error"))
+
+	buffer := &bytes.Buffer{}
+	written, err := metrics.WriteTo(buffer)
+	assert.Nil(t, err)
+	assert.Equal(t, int64(buffer.Len()), written)
+
+	assert.Equal(t, `# HELP couchcandy_requests_total Number of requests sent to CouchDB.
+# TYPE couchcandy_requests_total counter
+couchcandy_requests_total{endpoint="doc_read",method="GET",code="200"} 1
+couchcandy_requests_total{endpoint="doc_read",method="GET",code="404"} 1
+couchcandy_requests_total{endpoint="doc_read",method="GET",code="error"} 1
+# HELP couchcandy_request_duration_seconds Latency of the requests sent to CouchDB.
+# TYPE couchcandy_request_duration_seconds histogram
+couchcandy_request_duration_seconds_bucket{endpoint="doc_read",le="0.1"} 1
+couchcandy_request_duration_seconds_bucket{endpoint="doc_read",le="1"} 2
+couchcandy_request_duration_seconds_bucket{endpoint="doc_read",le="+Inf"} 3
+couchcandy_request_duration_seconds_sum{endpoint="doc_read"} 2.55
+couchcandy_request_duration_seconds_count{endpoint="doc_read"} 3
+`, buffer.String())
+
+}
+
+func TestMetricsInstrumentation(t *testing.T) {
+
+	couchcandy := NewCouchCandy(Session{
+		Host: "http://127.0.0.1", Port: 5984, Database: "lendr", Username: "test", Password: "gotest",
+	})
+	couchcandy.Transport = TransportFunc(func(req *http.Request) (*http.Response, error) {
+		return statusResponse(http.StatusCreated, `{"ok":true}`), nil
+	})
+	metrics := NewMetrics()
+	couchcandy.Instrumentation = metrics
+
+	_, err := couchcandy.AddWithID("card-1", map[string]string{"suit": "spades"})
+	assert.Nil(t, err)
+	assert.Equal(t, uint64(1), metrics.Requests(EndpointDocWrite, http.MethodPut, "201"))
+
+}
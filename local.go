@@ -0,0 +1,70 @@
+package couchcandy
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// LocalDocument Returns the specified _local document. Local documents are not
+// replicated and are not part of the changes feed, which makes them suitable for
+// checkpoints and per-node state. The id can be passed with or without the
+// "_local/" prefix.
+func (db *DB) LocalDocument(id string, v interface{}) error {
+
+	url := createLocalDocumentURL(db.session, id)
+	page, err := db.get(url)
+	if err != nil {
+		return err
+	}
+
+	unmarshallError := json.Unmarshal(page, v)
+	return unmarshallError
+
+}
+
+// PutLocal Creates or updates the _local document with the specified id. When
+// updating, the passed document must hold the current _rev.
+func (db *DB) PutLocal(id string, document interface{}) (*OperationResponse, error) {
+
+	url := createLocalDocumentURL(db.session, id)
+
+	bodyStr, marshallError := safeMarshall(document)
+	if marshallError != nil {
+		return nil, marshallError
+	}
+
+	page, err := db.putJSON(url, bodyStr)
+	if err != nil {
+		return nil, err
+	}
+
+	return toOperationResponse(page)
+
+}
+
+// DeleteLocal Deletes the _local document with the specified id and revision.
+func (db *DB) DeleteLocal(id, revision string) (*OperationResponse, error) {
+
+	url := fmt.Sprintf("%s?rev=%s", createLocalDocumentURL(db.session, id), revision)
+	page, err := db.delete(url)
+	if err != nil {
+		return nil, err
+	}
+
+	return toOperationResponse(page)
+
+}
+
+// LocalDocs Returns the _local documents of the database based on the passed
+// parameters, the same way Documents does for regular documents.
+func (db *DB) LocalDocs(options Options) (*AllDocuments, error) {
+
+	url := fmt.Sprintf("%s/_local_docs%s", createDatabaseURL(db.session), toQueryString(options))
+	page, err := db.get(url)
+	if err != nil {
+		return nil, err
+	}
+
+	return toAllDocuments(page)
+
+}
@@ -0,0 +1,177 @@
+package couchcandy
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"log/slog"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// reasonSample is the number of bytes of an error response kept to log its reason
+const reasonSample = 1024
+
+// LogOptions Tunes the logging of the requests.
+// Level : level of the requests that succeed, the requests failing with an
+// error or a 5xx status are logged at slog.LevelError
+// BodySample : number of bytes of the request and response bodies added to
+// the log entries, for debugging, 0 logs no body
+type LogOptions struct {
+	Level      slog.Level
+	BodySample int
+}
+
+// Logging Logs every request going through it on logger, with the method,
+// the url without the credentials, the status, the duration, the number of
+// bytes read and the reason of the CouchDB errors. The entry is written once
+// the response body is closed, so that streamed responses are measured
+// completely. The client adds it next to the transport when its Logger is
+// set, use it directly to place it elsewhere in the middlewares.
+func Logging(logger *slog.Logger, options LogOptions) Middleware {
+	return func(next CandyHTTPClient) CandyHTTPClient {
+		return TransportFunc(func(req *http.Request) (*http.Response, error) {
+
+			entry := &logEntry{
+				logger:  logger,
+				options: options,
+				ctx:     req.Context(),
+				start:   time.Now(),
+				attrs: []slog.Attr{
+					slog.String("method", req.Method),
+					slog.String("url", req.URL.Redacted()),
+				},
+			}
+
+			if options.BodySample > 0 && req.GetBody != nil {
+				if body, err := req.GetBody(); err == nil {
+					sample := make([]byte, options.BodySample)
+					n, _ := io.ReadFull(body, sample)
+					body.Close()
+					entry.attrs = append(entry.attrs, slog.String("request_body", string(sample[:n])))
+				}
+			}
+
+			res, err := next.Do(req)
+			if err != nil {
+				entry.failed(err)
+				return nil, err
+			}
+
+			entry.status = res.StatusCode
+			if res.Body == nil {
+				entry.write()
+				return res, nil
+			}
+
+			res.Body = &loggedBody{ReadCloser: res.Body, entry: entry}
+			return res, nil
+
+		})
+	}
+}
+
+type logEntry struct {
+	logger  *slog.Logger
+	options LogOptions
+	ctx     context.Context
+	start   time.Time
+	attrs   []slog.Attr
+	status  int
+	bytes   int64
+	sample  bytes.Buffer
+}
+
+// keep records the beginning of the response body, for the sample and the reason.
+func (e *logEntry) keep(p []byte) {
+
+	limit := e.options.BodySample
+	if e.status >= http.StatusBadRequest && limit < reasonSample {
+		limit = reasonSample
+	}
+
+	if room := limit - e.sample.Len(); room > 0 {
+		if len(p) > room {
+			p = p[:room]
+		}
+		e.sample.Write(p)
+	}
+
+}
+
+func (e *logEntry) failed(err error) {
+	e.attrs = append(e.attrs,
+		slog.Duration("duration", time.Since(e.start)),
+		slog.String("error", err.Error()),
+	)
+	e.logger.LogAttrs(e.ctx, slog.LevelError, "couchdb request failed", e.attrs...)
+}
+
+func (e *logEntry) write() {
+
+	attrs := append(e.attrs,
+		slog.Int("status", e.status),
+		slog.Duration("duration", time.Since(e.start)),
+		slog.Int64("bytes", e.bytes),
+	)
+
+	if e.status >= http.StatusBadRequest {
+		if couchError := toCouchError(e.sample.Bytes()); couchError != nil {
+			attrs = append(attrs, slog.String("reason", couchError.Error()))
+		}
+	}
+
+	if e.options.BodySample > 0 {
+		sample := e.sample.Bytes()
+		if len(sample) > e.options.BodySample {
+			sample = sample[:e.options.BodySample]
+		}
+		attrs = append(attrs, slog.String("response_body", string(sample)))
+	}
+
+	level := e.options.Level
+	if e.status >= http.StatusInternalServerError {
+		level = slog.LevelError
+	}
+
+	e.logger.LogAttrs(e.ctx, level, "couchdb request", attrs...)
+
+}
+
+// loggedBody counts the bytes read from a response body and writes the log
+// entry when it is closed.
+type loggedBody struct {
+	io.ReadCloser
+	entry *logEntry
+	mutex sync.Mutex
+	done  bool
+}
+
+func (b *loggedBody) Read(p []byte) (int, error) {
+
+	n, err := b.ReadCloser.Read(p)
+
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	if !b.done {
+		b.entry.bytes += int64(n)
+		b.entry.keep(p[:n])
+	}
+	return n, err
+
+}
+
+func (b *loggedBody) Close() error {
+
+	err := b.ReadCloser.Close()
+
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	if !b.done {
+		b.done = true
+		b.entry.write()
+	}
+	return err
+
+}
@@ -0,0 +1,179 @@
+package couchcandy
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// DefaultDurationBuckets are the upper bounds, in seconds, of the latency
+// histogram buckets used when none are passed to NewMetrics.
+var DefaultDurationBuckets = []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10}
+
+// Metrics An Instrumentation counting the requests and measuring their
+// latency per endpoint class. It is exposed in the Prometheus text format
+// with WriteTo, or as an http.Handler to mount on a /metrics route :
+//
+//	metrics := couchcandy.NewMetrics()
+//	client.Instrumentation = metrics
+//	http.Handle("/metrics", metrics)
+//
+// It exposes couchcandy_requests_total, labelled with endpoint, method and
+// code ("error" when no response was received), and the
+// couchcandy_request_duration_seconds histogram, labelled with endpoint.
+type Metrics struct {
+	mutex     sync.Mutex
+	buckets   []float64
+	requests  map[requestLabels]uint64
+	durations map[Endpoint]*histogram
+}
+
+type requestLabels struct {
+	endpoint Endpoint
+	method   string
+	code     string
+}
+
+type histogram struct {
+	counts []uint64
+	count  uint64
+	sum    float64
+}
+
+// NewMetrics Returns empty metrics with the passed histogram buckets, or
+// DefaultDurationBuckets when there are none.
+func NewMetrics(buckets ...float64) *Metrics {
+
+	if len(buckets) == 0 {
+		buckets = DefaultDurationBuckets
+	}
+	sorted := append([]float64(nil), buckets...)
+	sort.Float64s(sorted)
+
+	return &Metrics{
+		buckets:   sorted,
+		requests:  map[requestLabels]uint64{},
+		durations: map[Endpoint]*histogram{},
+	}
+
+}
+
+// OnRequestStart Leaves the request as is.
+func (m *Metrics) OnRequestStart(req *http.Request, endpoint Endpoint) *http.Request {
+	return nil
+}
+
+// OnRequestDone Counts the request and observes its duration.
+func (m *Metrics) OnRequestDone(req *http.Request, endpoint Endpoint, res *http.Response, err error, duration time.Duration) {
+
+	code := "error"
+	if err == nil {
+		code = strconv.Itoa(res.StatusCode)
+	}
+
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	m.requests[requestLabels{endpoint: endpoint, method: req.Method, code: code}]++
+
+	h, found := m.durations[endpoint]
+	if !found {
+		h = &histogram{counts: make([]uint64, len(m.buckets))}
+		m.durations[endpoint] = h
+	}
+
+	seconds := duration.Seconds()
+	for i, bound := range m.buckets {
+		if seconds <= bound {
+			h.counts[i]++
+		}
+	}
+	h.count++
+	h.sum += seconds
+
+}
+
+// Requests Returns the number of requests counted for the labels.
+func (m *Metrics) Requests(endpoint Endpoint, method string, code string) uint64 {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	return m.requests[requestLabels{endpoint: endpoint, method: method, code: code}]
+}
+
+// WriteTo Writes the metrics in the Prometheus text exposition format.
+func (m *Metrics) WriteTo(w io.Writer) (int64, error) {
+
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	counter := &countingWriter{writer: bufio.NewWriter(w)}
+
+	fmt.Fprintln(counter, "# HELP couchcandy_requests_total Number of requests sent to CouchDB.")
+	fmt.Fprintln(counter, "# TYPE couchcandy_requests_total counter")
+	labels := make([]requestLabels, 0, len(m.requests))
+	for l := range m.requests {
+		labels = append(labels, l)
+	}
+	sort.Slice(labels, func(i, j int) bool {
+		if labels[i].endpoint != labels[j].endpoint {
+			return labels[i].endpoint < labels[j].endpoint
+		}
+		if labels[i].method != labels[j].method {
+			return labels[i].method < labels[j].method
+		}
+		return labels[i].code < labels[j].code
+	})
+	for _, l := range labels {
+		fmt.Fprintf(counter, "couchcandy_requests_total{endpoint=%q,method=%q,code=%q} %d\n", l.endpoint, l.method, l.code, m.requests[l])
+	}
+
+	fmt.Fprintln(counter, "# HELP couchcandy_request_duration_seconds Latency of the requests sent to CouchDB.")
+	fmt.Fprintln(counter, "# TYPE couchcandy_request_duration_seconds histogram")
+	endpoints := make([]Endpoint, 0, len(m.durations))
+	for endpoint := range m.durations {
+		endpoints = append(endpoints, endpoint)
+	}
+	sort.Slice(endpoints, func(i, j int) bool { return endpoints[i] < endpoints[j] })
+	for _, endpoint := range endpoints {
+		h := m.durations[endpoint]
+		for i, bound := range m.buckets {
+			fmt.Fprintf(counter, "couchcandy_request_duration_seconds_bucket{endpoint=%q,le=%q} %d\n", endpoint, strconv.FormatFloat(bound, 'g', -1, 64), h.counts[i])
+		}
+		fmt.Fprintf(counter, "couchcandy_request_duration_seconds_bucket{endpoint=%q,le=\"+Inf\"} %d\n", endpoint, h.count)
+		fmt.Fprintf(counter, "couchcandy_request_duration_seconds_sum{endpoint=%q} %s\n", endpoint, strconv.FormatFloat(h.sum, 'g', -1, 64))
+		fmt.Fprintf(counter, "couchcandy_request_duration_seconds_count{endpoint=%q} %d\n", endpoint, h.count)
+	}
+
+	if counter.err != nil {
+		return counter.written, counter.err
+	}
+	return counter.written, counter.writer.Flush()
+
+}
+
+// ServeHTTP Serves the metrics in the Prometheus text exposition format.
+func (m *Metrics) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set(HeaderContentType, "text/plain; version=0.0.4")
+	m.WriteTo(w)
+}
+
+type countingWriter struct {
+	writer  *bufio.Writer
+	written int64
+	err     error
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	if c.err != nil {
+		return 0, c.err
+	}
+	n, err := c.writer.Write(p)
+	c.written += int64(n)
+	c.err = err
+	return n, err
+}
@@ -0,0 +1,271 @@
+package couchcandy
+
+import (
+	"bytes"
+	"container/list"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// HeaderIfNoneMatch is the header holding the revision known to the client
+const HeaderIfNoneMatch string = "If-None-Match"
+
+// Middleware Wraps a transport to act on the requests going through it, in
+// the manner of an http.RoundTripper. Middlewares are used for auth, retries,
+// logging, metrics or caching.
+type Middleware func(next CandyHTTPClient) CandyHTTPClient
+
+// TransportFunc Adapts a function to the CandyHTTPClient interface, which
+// makes writing middlewares and test transports easier.
+type TransportFunc func(req *http.Request) (*http.Response, error)
+
+// Do Calls f(req).
+func (f TransportFunc) Do(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+// Use Appends middlewares to the client. The first middleware added is the
+// outermost one, it sees the request first and the response last. Middlewares
+// should be added before the client is shared between goroutines.
+func (c *CouchCandy) Use(middlewares ...Middleware) *CouchCandy {
+	c.Middlewares = append(c.Middlewares, middlewares...)
+	return c
+}
+
+// transport returns the transport of the client wrapped in its middlewares.
+func (c *CouchCandy) transport() CandyHTTPClient {
+
+	var client CandyHTTPClient = http.DefaultClient
+	if c.Transport != nil {
+		client = c.Transport
+	}
+
+	if c.Logger != nil {
+		client = Logging(c.Logger, c.LogOptions)(client)
+	}
+	client = propagateTraceParent(client)
+	if c.Instrumentation != nil {
+		client = Instrument(c.Instrumentation)(client)
+	}
+
+	for i := len(c.Middlewares) - 1; i >= 0; i-- {
+		client = c.Middlewares[i](client)
+	}
+	return client
+
+}
+
+// BasicAuth Sets the credentials of every request going through the client,
+// for when they are not part of the url.
+func BasicAuth(username, password string) Middleware {
+	return func(next CandyHTTPClient) CandyHTTPClient {
+		return TransportFunc(func(req *http.Request) (*http.Response, error) {
+			req = req.Clone(req.Context())
+			req.SetBasicAuth(username, password)
+			return next.Do(req)
+		})
+	}
+}
+
+// Retry Retries the idempotent requests (GET and HEAD) failing with an error,
+// a 5xx status or a 429 status, up to attempts times in total. The wait
+// between two attempts starts at backoff and doubles each time, unless the
+// server sends a Retry-After header.
+func Retry(attempts int, backoff time.Duration) Middleware {
+	return func(next CandyHTTPClient) CandyHTTPClient {
+		return TransportFunc(func(req *http.Request) (*http.Response, error) {
+
+			if req.Method != http.MethodGet && req.Method != http.MethodHead {
+				return next.Do(req)
+			}
+
+			wait := backoff
+			for attempt := 1; ; attempt++ {
+
+				res, err := next.Do(req)
+				if attempt >= attempts || !retryable(res, err) {
+					return res, err
+				}
+
+				delay := wait
+				if res != nil {
+					delay = retryAfter(res, wait)
+					if res.Body != nil {
+						res.Body.Close()
+					}
+				}
+
+				timer := time.NewTimer(delay)
+				select {
+				case <-req.Context().Done():
+					timer.Stop()
+					return nil, req.Context().Err()
+				case <-timer.C:
+				}
+				wait *= 2
+
+			}
+
+		})
+	}
+}
+
+func retryable(res *http.Response, err error) bool {
+	if err != nil {
+		return true
+	}
+	return res.StatusCode >= http.StatusInternalServerError || res.StatusCode == http.StatusTooManyRequests
+}
+
+func retryAfter(res *http.Response, fallback time.Duration) time.Duration {
+	seconds, err := strconv.Atoi(res.Header.Get("Retry-After"))
+	if err != nil || seconds < 0 {
+		return fallback
+	}
+	return time.Duration(seconds) * time.Second
+}
+
+// Cache Keeps the GET responses carrying an ETag, up to size of them, and
+// revalidates them with If-None-Match. When CouchDB answers 304 Not Modified,
+// the kept response is served without downloading the body again. The least
+// recently used responses are dropped first. The responses are kept per
+// credentials and cookies, which Cache only sees when it comes after the
+// middlewares setting them. Placed before them, every response kept is still
+// revalidated by CouchDB with the credentials of the request.
+func Cache(size int) Middleware {
+	cache := &etagCache{size: size, entries: map[string]*list.Element{}, order: list.New()}
+	return func(next CandyHTTPClient) CandyHTTPClient {
+		return TransportFunc(func(req *http.Request) (*http.Response, error) {
+
+			if req.Method != http.MethodGet || req.Header.Get(HeaderIfNoneMatch) != "" {
+				return next.Do(req)
+			}
+
+			key := cacheKey(req)
+			cached, found := cache.get(key)
+			if found {
+				req = req.Clone(req.Context())
+				req.Header.Set(HeaderIfNoneMatch, cached.etag)
+			}
+
+			res, err := next.Do(req)
+			if err != nil {
+				return nil, err
+			}
+
+			if found && res.StatusCode == http.StatusNotModified {
+				res.Body.Close()
+				return cached.response(req), nil
+			}
+
+			etag := res.Header.Get(HeaderETag)
+			if res.StatusCode != http.StatusOK || etag == "" {
+				return res, nil
+			}
+
+			body, err := ioutil.ReadAll(res.Body)
+			res.Body.Close()
+			if err != nil {
+				return nil, err
+			}
+			res.Body = ioutil.NopCloser(bytes.NewReader(body))
+
+			cache.put(key, &cachedResponse{etag: etag, header: res.Header.Clone(), body: body})
+			return res, nil
+
+		})
+	}
+}
+
+// cacheKey identifies the response to a request by its url without the
+// credentials, and by a hash of the credentials and cookies it is sent with,
+// so that users who can read different documents never share a response.
+func cacheKey(req *http.Request) string {
+
+	target := *req.URL
+	target.User = nil
+
+	authorization := req.Header.Get("Authorization")
+	if authorization == "" && req.URL.User != nil {
+		// The credentials of the url are sent as basic auth.
+		password, _ := req.URL.User.Password()
+		authorization = "Basic " + base64.StdEncoding.EncodeToString([]byte(req.URL.User.Username()+":"+password))
+	}
+
+	credentials := sha256.Sum256([]byte(authorization + "\n" + req.Header.Get("Cookie")))
+	return hex.EncodeToString(credentials[:]) + " " + target.String()
+
+}
+
+type cachedResponse struct {
+	key    string
+	etag   string
+	header http.Header
+	body   []byte
+}
+
+func (c *cachedResponse) response(req *http.Request) *http.Response {
+	return &http.Response{
+		Status:        "200 OK",
+		StatusCode:    http.StatusOK,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        c.header.Clone(),
+		Body:          ioutil.NopCloser(bytes.NewReader(c.body)),
+		ContentLength: int64(len(c.body)),
+		Request:       req,
+	}
+}
+
+type etagCache struct {
+	mutex   sync.Mutex
+	size    int
+	entries map[string]*list.Element
+	order   *list.List
+}
+
+func (c *etagCache) get(key string) (*cachedResponse, bool) {
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	element, found := c.entries[key]
+	if !found {
+		return nil, false
+	}
+	c.order.MoveToFront(element)
+	return element.Value.(*cachedResponse), true
+
+}
+
+func (c *etagCache) put(key string, response *cachedResponse) {
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if c.size <= 0 {
+		return
+	}
+
+	response.key = key
+	if element, found := c.entries[key]; found {
+		element.Value = response
+		c.order.MoveToFront(element)
+		return
+	}
+
+	c.entries[key] = c.order.PushFront(response)
+	for c.order.Len() > c.size {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.entries, oldest.Value.(*cachedResponse).key)
+	}
+
+}
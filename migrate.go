@@ -0,0 +1,389 @@
+package couchcandy
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+const (
+	// DefaultMigrationRecord is the id of the document recording the applied migrations
+	DefaultMigrationRecord string = "_local/migrations"
+	// DefaultTypeField is the field holding the type of the documents
+	DefaultTypeField string = "type"
+)
+
+// Migration A change of the shape of some documents. Migrations are
+// identified by their ID, which must never change once applied.
+// ID : identifies the migration in the record of the applied ones
+// Type : migrates the documents whose type field holds it, through _find
+// Selector : migrates the documents matching the Mango selector, through _find, along with Type if set
+// Transform : changes the document in place and tells whether it changed
+//
+// All the documents but the design documents are migrated, through
+// _all_docs, when neither Type nor Selector is set. The numbers of the
+// documents are json.Number values, so that they are written back as they
+// were read. Transform must leave the documents already in shape unchanged,
+// as the documents of an interrupted migration can be read again.
+type Migration struct {
+	ID          string
+	Description string
+	Type        string
+	Selector    interface{}
+	Transform   func(doc map[string]interface{}) (bool, error)
+}
+
+// MigrationResult The outcome of a migration, also reported as it makes
+// progress. Skipped tells that the migration was already applied. Scanned
+// counts the documents read, the record included when it is a regular
+// document, Changed those Transform changed and Written those saved, none in
+// a dry run. Failed lists the documents CouchDB rejected.
+type MigrationResult struct {
+	ID      string
+	Skipped bool
+	Scanned int
+	Changed int
+	Written int
+	Failed  []OperationResponse
+}
+
+// AppliedMigration When a migration was applied, and to how many documents.
+type AppliedMigration struct {
+	At        time.Time `json:"at"`
+	Documents int       `json:"documents"`
+}
+
+// Migrator Applies migrations to the documents of a database, in order, and
+// records the migrations applied in a document so that each one is only
+// applied once. The record is the RecordID document, a _local document by
+// default so that it is not replicated, or a regular document when the id has
+// no "_local/" prefix.
+//
+// The documents are read and written a batch at a time. Between batches, the
+// position reached is saved in the record, and a migration that stopped on an
+// error resumes from there on the next run, unless its Type or Selector
+// changed in between. When some documents could not be
+// written, because of conflicts for instance, the migration is not recorded
+// and the next run goes over all the documents again.
+//
+// In a dry run, the documents are transformed but neither they nor the
+// record are written.
+type Migrator struct {
+	RecordID  string
+	TypeField string
+	BatchSize int
+	DryRun    bool
+	Progress  func(MigrationResult)
+	db        *DB
+}
+
+// migrationRecord is the document recording the applied migrations and the
+// position reached by the pending one.
+type migrationRecord struct {
+	ID      string                      `json:"_id,omitempty"`
+	REV     string                      `json:"_rev,omitempty"`
+	Applied map[string]AppliedMigration `json:"applied"`
+	Pending *pendingMigration           `json:"pending,omitempty"`
+	Error   string                      `json:"error,omitempty"`
+	Reason  string                      `json:"reason,omitempty"`
+}
+
+// pendingMigration is the position reached by a migration. The bookmarks of
+// _find only hold for the selector they were returned for, whose hash is
+// kept along.
+type pendingMigration struct {
+	ID       string `json:"id"`
+	Cursor   string `json:"cursor"`
+	Selector string `json:"selector,omitempty"`
+}
+
+// Migrator Returns a migrator of the documents of the database.
+func (db *DB) Migrator() *Migrator {
+	return &Migrator{
+		RecordID:  DefaultMigrationRecord,
+		TypeField: DefaultTypeField,
+		BatchSize: DefaultBatchSize,
+		db:        db,
+	}
+}
+
+// Applied Returns the migrations applied so far, by id.
+func (m *Migrator) Applied() (map[string]AppliedMigration, error) {
+
+	record, err := m.load()
+	if err != nil {
+		return nil, err
+	}
+	return record.Applied, nil
+
+}
+
+// Run Applies the migrations that were not applied yet, in order, and stops
+// at the first one that fails.
+func (m *Migrator) Run(migrations []Migration) ([]MigrationResult, error) {
+
+	for _, migration := range migrations {
+		if migration.ID == "" || migration.Transform == nil {
+			return nil, errors.New("migration: a migration has no ID or no Transform")
+		}
+	}
+
+	record, err := m.load()
+	if err != nil {
+		return nil, err
+	}
+
+	results := make([]MigrationResult, 0, len(migrations))
+	for _, migration := range migrations {
+
+		if _, ok := record.Applied[migration.ID]; ok {
+			results = append(results, MigrationResult{ID: migration.ID, Skipped: true})
+			continue
+		}
+
+		selector, err := m.selectorHash(migration)
+		if err != nil {
+			return results, fmt.Errorf("migration %s: %v", migration.ID, err)
+		}
+
+		position := &pendingMigration{ID: migration.ID, Selector: selector}
+		if pending := record.Pending; pending != nil && pending.ID == migration.ID && pending.Selector == selector {
+			position.Cursor = pending.Cursor
+		}
+
+		result, err := m.migrate(record, migration, position)
+		results = append(results, *result)
+		if err != nil {
+			return results, err
+		}
+
+	}
+	return results, nil
+
+}
+
+// migrate applies the migration from the position on.
+func (m *Migrator) migrate(record *migrationRecord, migration Migration, position *pendingMigration) (*MigrationResult, error) {
+
+	result := &MigrationResult{ID: migration.ID}
+	batchSize := m.BatchSize
+	if batchSize <= 0 {
+		batchSize = DefaultBatchSize
+	}
+
+	for {
+
+		docs, next, done, err := m.page(migration, position.Cursor, batchSize)
+		if err != nil {
+			return result, fmt.Errorf("migration %s: %v", migration.ID, err)
+		}
+
+		changed := make([]map[string]interface{}, 0, len(docs))
+		for _, raw := range docs {
+
+			doc := make(map[string]interface{})
+			decoder := json.NewDecoder(bytes.NewReader(raw))
+			decoder.UseNumber()
+			if err = decoder.Decode(&doc); err != nil {
+				return result, fmt.Errorf("migration %s: %v", migration.ID, err)
+			}
+
+			result.Scanned++
+			if doc["_id"] == m.RecordID {
+				continue
+			}
+
+			ok, err := migration.Transform(doc)
+			if err != nil {
+				return result, fmt.Errorf("migration %s: %v: %v", migration.ID, doc["_id"], err)
+			}
+			if ok {
+				changed = append(changed, doc)
+			}
+
+		}
+		result.Changed += len(changed)
+
+		if !m.DryRun && len(changed) != 0 {
+			responses, err := m.db.BulkDocs(changed, BulkDocsOptions{})
+			if err != nil {
+				return result, fmt.Errorf("migration %s: %v", migration.ID, err)
+			}
+			for _, response := range responses {
+				if response.Error != "" {
+					result.Failed = append(result.Failed, response)
+					continue
+				}
+				result.Written++
+			}
+		}
+
+		position.Cursor = next
+		if !m.DryRun && !done {
+			record.Pending = position
+			if err = m.save(record); err != nil {
+				return result, err
+			}
+		}
+
+		if m.Progress != nil {
+			m.Progress(*result)
+		}
+		if done {
+			break
+		}
+
+	}
+
+	if m.DryRun {
+		return result, nil
+	}
+
+	record.Pending = nil
+	if len(result.Failed) == 0 {
+		record.Applied[migration.ID] = AppliedMigration{At: time.Now().UTC(), Documents: result.Written}
+	}
+	if err := m.save(record); err != nil {
+		return result, err
+	}
+
+	if len(result.Failed) != 0 {
+		return result, fmt.Errorf("migration %s: %d documents were not written", migration.ID, len(result.Failed))
+	}
+	return result, nil
+
+}
+
+// page reads the documents of the migration that follow the cursor, the id
+// of the last document read from _all_docs or the bookmark of _find, and
+// returns the next cursor.
+func (m *Migrator) page(migration Migration, cursor string, batchSize int) ([]json.RawMessage, string, bool, error) {
+
+	if selector := m.selector(migration); selector != nil {
+
+		response, err := m.db.Find(FindQuery{Selector: selector, Limit: batchSize, Bookmark: cursor})
+		if err != nil {
+			return nil, "", false, err
+		}
+		if response.Error != "" {
+			return nil, "", false, fmt.Errorf("%s: %s", response.Error, response.Reason)
+		}
+		return response.Docs, response.Bookmark, len(response.Docs) < batchSize, nil
+
+	}
+
+	options := Options{IncludeDocs: true, Limit: batchSize}
+	if cursor != "" {
+		startKey, _ := json.Marshal(cursor)
+		options.StartKey = string(startKey)
+		options.Skip = 1
+	}
+
+	allDocuments, err := m.db.Documents(options)
+	if err != nil {
+		return nil, "", false, err
+	}
+	if allDocuments.Error != "" {
+		return nil, "", false, fmt.Errorf("%s: %s", allDocuments.Error, allDocuments.Reason)
+	}
+
+	docs := make([]json.RawMessage, 0, len(allDocuments.Rows))
+	for _, row := range allDocuments.Rows {
+		if !strings.HasPrefix(row.ID, designPrefix) {
+			docs = append(docs, row.Doc)
+		}
+	}
+	if len(allDocuments.Rows) != 0 {
+		cursor = allDocuments.Rows[len(allDocuments.Rows)-1].ID
+	}
+	return docs, cursor, len(allDocuments.Rows) < batchSize, nil
+
+}
+
+// selector returns the Mango selector of the documents of the migration,
+// nil when all the documents are migrated.
+func (m *Migrator) selector(migration Migration) interface{} {
+
+	selector := migration.Selector
+	if migration.Type != "" {
+		selector = map[string]interface{}{m.TypeField: migration.Type}
+		if migration.Selector != nil {
+			selector = map[string]interface{}{"$and": []interface{}{selector, migration.Selector}}
+		}
+	}
+	return selector
+
+}
+
+// selectorHash returns the hash of the selector of the migration, empty
+// when all the documents are migrated.
+func (m *Migrator) selectorHash(migration Migration) (string, error) {
+
+	selector := m.selector(migration)
+	if selector == nil {
+		return "", nil
+	}
+
+	body, err := json.Marshal(selector)
+	if err != nil {
+		return "", err
+	}
+	hash := sha256.Sum256(body)
+	return hex.EncodeToString(hash[:]), nil
+
+}
+
+func (m *Migrator) load() (*migrationRecord, error) {
+
+	record := &migrationRecord{}
+	var err error
+	if strings.HasPrefix(m.RecordID, localPrefix) {
+		err = m.db.LocalDocument(m.RecordID, record)
+	} else {
+		err = m.db.Document(m.RecordID, record, Options{})
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	switch record.Error {
+	case "":
+	case "not_found":
+		record = &migrationRecord{}
+	default:
+		return nil, fmt.Errorf("%s: %s", record.Error, record.Reason)
+	}
+
+	if record.Applied == nil {
+		record.Applied = make(map[string]AppliedMigration)
+	}
+	return record, nil
+
+}
+
+func (m *Migrator) save(record *migrationRecord) error {
+
+	record.ID = m.RecordID
+	var response *OperationResponse
+	var err error
+	if strings.HasPrefix(m.RecordID, localPrefix) {
+		response, err = m.db.PutLocal(m.RecordID, record)
+	} else {
+		response, err = m.db.AddWithID(m.RecordID, record)
+	}
+	if err != nil {
+		return err
+	}
+	if response.Error != "" {
+		return fmt.Errorf("%s: %s", response.Error, response.Reason)
+	}
+
+	record.REV = response.REV
+	return nil
+
+}
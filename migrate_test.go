@@ -0,0 +1,354 @@
+package couchcandy
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// migrationServer is an in-memory database answering the requests of a
+// migrator : _all_docs, _find on the type field, _bulk_docs and the record.
+// It records the bookmarks sent to _find.
+type migrationServer struct {
+	*router
+	docs      map[string]map[string]interface{}
+	record    string
+	bookmarks []string
+}
+
+func newMigrationServer(t *testing.T, couchcandy *CouchCandy, docs ...string) *migrationServer {
+
+	server := &migrationServer{router: route(couchcandy), docs: make(map[string]map[string]interface{})}
+	for _, doc := range docs {
+		fields := make(map[string]interface{})
+		decoder := json.NewDecoder(strings.NewReader(doc))
+		decoder.UseNumber()
+		assert.Nil(t, decoder.Decode(&fields))
+		fields["_rev"] = "1-a"
+		server.docs[fields["_id"].(string)] = fields
+	}
+
+	server.
+		Handle(http.MethodGet, "/lendr/_local/migrations", func(*http.Request, []byte) (*http.Response, error) {
+			if server.record == "" {
+				return statusResponse(http.StatusNotFound, `{"error":"not_found","reason":"missing"}`), nil
+			}
+			return statusResponse(http.StatusOK, server.record), nil
+		}).
+		Handle(http.MethodPut, "/lendr/_local/migrations", func(req *http.Request, body []byte) (*http.Response, error) {
+			server.record = string(body)
+			return statusResponse(http.StatusCreated, `{"ok":true,"id":"_local/migrations","rev":"0-1"}`), nil
+		}).
+		Handle(http.MethodGet, "/lendr/_all_docs", func(req *http.Request, body []byte) (*http.Response, error) {
+			query := req.URL.Query()
+			ids := server.ids(func(map[string]interface{}) bool { return true })
+			start := 0
+			if key := query.Get("start_key"); key != "" {
+				var id string
+				assert.Nil(t, json.Unmarshal([]byte(key), &id))
+				start = sort.SearchStrings(ids, id)
+			}
+			skip, _ := strconv.Atoi(query.Get("skip"))
+			limit, _ := strconv.Atoi(query.Get("limit"))
+			rows := make([]string, 0)
+			for _, id := range server.window(ids, start+skip, limit) {
+				doc, _ := json.Marshal(server.docs[id])
+				rows = append(rows, fmt.Sprintf(`{"id":%q,"key":%q,"value":{"rev":%q},"doc":%s}`, id, id, server.docs[id]["_rev"], doc))
+			}
+			return statusResponse(http.StatusOK, `{"rows":[`+strings.Join(rows, ",")+`]}`), nil
+		}).
+		Handle(http.MethodPost, "/lendr/_find", func(req *http.Request, body []byte) (*http.Response, error) {
+			query := &struct {
+				Selector map[string]string `json:"selector"`
+				Limit    int               `json:"limit"`
+				Bookmark string            `json:"bookmark"`
+			}{}
+			assert.Nil(t, json.Unmarshal(body, query))
+			server.bookmarks = append(server.bookmarks, query.Bookmark)
+			ids := server.ids(func(doc map[string]interface{}) bool { return doc["type"] == query.Selector["type"] })
+			start, _ := strconv.Atoi(query.Bookmark)
+			docs := make([]string, 0)
+			for _, id := range server.window(ids, start, query.Limit) {
+				doc, _ := json.Marshal(server.docs[id])
+				docs = append(docs, string(doc))
+			}
+			return statusResponse(http.StatusOK, fmt.Sprintf(`{"docs":[%s],"bookmark":"%d"}`, strings.Join(docs, ","), start+len(docs))), nil
+		}).
+		Handle(http.MethodPost, "/lendr/_bulk_docs", func(req *http.Request, body []byte) (*http.Response, error) {
+			request := &struct {
+				Docs []map[string]interface{} `json:"docs"`
+			}{}
+			decoder := json.NewDecoder(bytes.NewReader(body))
+			decoder.UseNumber()
+			assert.Nil(t, decoder.Decode(request))
+			responses := make([]string, 0)
+			for _, doc := range request.Docs {
+				id := doc["_id"].(string)
+				if doc["_rev"] != server.docs[id]["_rev"] {
+					responses = append(responses, fmt.Sprintf(`{"id":%q,"error":"conflict","reason":"Document update conflict."}`, id))
+					continue
+				}
+				doc["_rev"] = "2-b"
+				server.docs[id] = doc
+				responses = append(responses, fmt.Sprintf(`{"ok":true,"id":%q,"rev":"2-b"}`, id))
+			}
+			return statusResponse(http.StatusCreated, `[`+strings.Join(responses, ",")+`]`), nil
+		})
+	return server
+
+}
+
+func (s *migrationServer) ids(match func(map[string]interface{}) bool) []string {
+	ids := make([]string, 0)
+	for id, doc := range s.docs {
+		if match(doc) {
+			ids = append(ids, id)
+		}
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+func (s *migrationServer) window(ids []string, start, limit int) []string {
+	if start > len(ids) {
+		start = len(ids)
+	}
+	if end := start + limit; limit > 0 && end < len(ids) {
+		return ids[start:end]
+	}
+	return ids[start:]
+}
+
+// addVisibility moves the public flag of the user profiles to a visibility
+// field.
+var addVisibility = Migration{
+	ID:   "2024-01-profile-visibility",
+	Type: "profile",
+	Transform: func(doc map[string]interface{}) (bool, error) {
+		if _, ok := doc["visibility"]; ok {
+			return false, nil
+		}
+		doc["visibility"] = "private"
+		if doc["public"] == true {
+			doc["visibility"] = "public"
+		}
+		delete(doc, "public")
+		return true, nil
+	},
+}
+
+func TestMigrate(t *testing.T) {
+
+	couchcandy := newTestClient()
+	server := newMigrationServer(t, couchcandy,
+		`{"_id":"_design/profiles","views":{}}`,
+		`{"_id":"card-1","type":"card","rank":12345678901234567890}`,
+		`{"_id":"user-1","type":"profile","public":true}`,
+		`{"_id":"user-2","type":"profile","visibility":"public"}`,
+		`{"_id":"user-3","type":"profile"}`,
+	)
+
+	stampAll := Migration{
+		ID: "2024-02-schema-version",
+		Transform: func(doc map[string]interface{}) (bool, error) {
+			doc["schema"] = 2
+			return true, nil
+		},
+	}
+
+	progress := make([]MigrationResult, 0)
+	migrator := couchcandy.DB("lendr").Migrator()
+	migrator.BatchSize = 2
+	migrator.Progress = func(result MigrationResult) { progress = append(progress, result) }
+
+	results, err := migrator.Run([]Migration{addVisibility, stampAll})
+	assert.Nil(t, err)
+	assert.Equal(t, []MigrationResult{
+		{ID: addVisibility.ID, Scanned: 3, Changed: 2, Written: 2},
+		{ID: stampAll.ID, Scanned: 4, Changed: 4, Written: 4},
+	}, results)
+	assert.Len(t, progress, 5)
+	assert.Equal(t, MigrationResult{ID: addVisibility.ID, Scanned: 2, Changed: 1, Written: 1}, progress[0])
+
+	assert.Equal(t, "public", server.docs["user-1"]["visibility"])
+	assert.NotContains(t, server.docs["user-1"], "public")
+	assert.Equal(t, "private", server.docs["user-3"]["visibility"])
+	assert.NotContains(t, server.docs["_design/profiles"], "schema")
+	assert.Equal(t, json.Number("12345678901234567890"), server.docs["card-1"]["rank"])
+
+	applied, err := migrator.Applied()
+	assert.Nil(t, err)
+	assert.Equal(t, 2, applied[addVisibility.ID].Documents)
+	assert.False(t, applied[stampAll.ID].At.IsZero())
+	assert.NotContains(t, server.record, "pending")
+
+	server.Reset()
+	results, err = migrator.Run([]Migration{addVisibility, stampAll})
+	assert.Nil(t, err)
+	assert.Equal(t, []MigrationResult{{ID: addVisibility.ID, Skipped: true}, {ID: stampAll.ID, Skipped: true}}, results)
+	assert.Equal(t, []string{"GET /lendr/_local/migrations?"}, server.Requests())
+
+}
+
+func TestMigrateDryRun(t *testing.T) {
+
+	couchcandy := newTestClient()
+	server := newMigrationServer(t, couchcandy, `{"_id":"user-1","type":"profile","public":true}`)
+
+	migrator := couchcandy.DB("lendr").Migrator()
+	migrator.DryRun = true
+	results, err := migrator.Run([]Migration{addVisibility})
+	assert.Nil(t, err)
+	assert.Equal(t, []MigrationResult{{ID: addVisibility.ID, Scanned: 1, Changed: 1}}, results)
+	assert.Equal(t, true, server.docs["user-1"]["public"])
+	assert.Equal(t, "", server.record)
+
+}
+
+func TestMigrateResume(t *testing.T) {
+
+	couchcandy := newTestClient()
+	server := newMigrationServer(t, couchcandy,
+		`{"_id":"card-1","suit":"hearts"}`,
+		`{"_id":"card-2","suit":"spades"}`,
+		`{"_id":"card-3","suit":"joker"}`,
+	)
+
+	failing := true
+	upperSuits := Migration{
+		ID: "2024-03-upper-suits",
+		Transform: func(doc map[string]interface{}) (bool, error) {
+			if doc["suit"] == "joker" && failing {
+				return false, errors.New("unknown suit")
+			}
+			doc["suit"] = strings.ToUpper(doc["suit"].(string))
+			return true, nil
+		},
+	}
+
+	migrator := couchcandy.DB("lendr").Migrator()
+	migrator.BatchSize = 2
+	results, err := migrator.Run([]Migration{upperSuits})
+	assert.EqualError(t, err, "migration 2024-03-upper-suits: card-3: unknown suit")
+	assert.Equal(t, 2, results[0].Written)
+	assert.JSONEq(t, `{"_id":"_local/migrations","applied":{},"pending":{"id":"2024-03-upper-suits","cursor":"card-2"}}`, server.record)
+
+	failing = false
+	server.Reset()
+	results, err = migrator.Run([]Migration{upperSuits})
+	assert.Nil(t, err)
+	assert.Equal(t, []MigrationResult{{ID: upperSuits.ID, Scanned: 1, Changed: 1, Written: 1}}, results)
+	assert.Contains(t, server.Requests()[1], "start_key=%22card-2%22")
+	assert.Equal(t, "JOKER", server.docs["card-3"]["suit"])
+	assert.Equal(t, "HEARTS", server.docs["card-1"]["suit"])
+
+}
+
+func TestMigrateResumeBookmark(t *testing.T) {
+
+	couchcandy := newTestClient()
+	server := newMigrationServer(t, couchcandy,
+		`{"_id":"user-1","type":"profile"}`,
+		`{"_id":"user-2","type":"profile","public":"maybe"}`,
+		`{"_id":"user-3","type":"profile"}`,
+		`{"_id":"admin-1","type":"admin","public":"maybe"}`,
+	)
+
+	failing := true
+	strict := addVisibility
+	strict.Transform = func(doc map[string]interface{}) (bool, error) {
+		if doc["public"] == "maybe" && failing {
+			return false, errors.New("not a boolean")
+		}
+		return addVisibility.Transform(doc)
+	}
+
+	migrator := couchcandy.DB("lendr").Migrator()
+	migrator.BatchSize = 1
+	_, err := migrator.Run([]Migration{strict})
+	assert.EqualError(t, err, "migration 2024-01-profile-visibility: user-2: not a boolean")
+	assert.Contains(t, server.record, `"cursor":"1"`)
+	assert.Equal(t, []string{"", "1"}, server.bookmarks)
+
+	// The bookmark is discarded when the selector changed.
+	server.bookmarks = nil
+	admins := strict
+	admins.Type = "admin"
+	_, err = migrator.Run([]Migration{admins})
+	assert.EqualError(t, err, "migration 2024-01-profile-visibility: admin-1: not a boolean")
+	assert.Equal(t, []string{""}, server.bookmarks)
+
+	server.bookmarks = nil
+	_, err = migrator.Run([]Migration{strict})
+	assert.NotNil(t, err)
+	assert.Equal(t, []string{"1"}, server.bookmarks)
+
+	failing = false
+	server.bookmarks = nil
+	results, err := migrator.Run([]Migration{strict})
+	assert.Nil(t, err)
+	assert.Equal(t, []MigrationResult{{ID: strict.ID, Scanned: 2, Changed: 2, Written: 2}}, results)
+	assert.Equal(t, []string{"1", "2", "3"}, server.bookmarks)
+	assert.Equal(t, "private", server.docs["user-2"]["visibility"])
+	assert.NotContains(t, server.record, "pending")
+
+}
+
+func TestMigrateRegularRecord(t *testing.T) {
+
+	couchcandy := newTestClient()
+	server := newMigrationServer(t, couchcandy, `{"_id":"migrations","applied":{}}`, `{"_id":"user-1","type":"profile"}`)
+	server.
+		Reply(http.MethodGet, "/lendr/migrations", http.StatusNotFound, `{"error":"not_found","reason":"missing"}`).
+		Reply(http.MethodPut, "/lendr/migrations", http.StatusCreated, `{"ok":true,"id":"migrations","rev":"2-a"}`)
+
+	transformed := make([]interface{}, 0)
+	stamp := Migration{
+		ID: "2024-04-stamp",
+		Transform: func(doc map[string]interface{}) (bool, error) {
+			transformed = append(transformed, doc["_id"])
+			return false, nil
+		},
+	}
+
+	migrator := couchcandy.DB("lendr").Migrator()
+	migrator.RecordID = "migrations"
+	results, err := migrator.Run([]Migration{stamp})
+	assert.Nil(t, err)
+	assert.Equal(t, []MigrationResult{{ID: stamp.ID, Scanned: 2}}, results)
+	assert.Equal(t, []interface{}{"user-1"}, transformed)
+
+}
+
+func TestMigrateConflicts(t *testing.T) {
+
+	couchcandy := newTestClient()
+	server := newMigrationServer(t, couchcandy, `{"_id":"user-1","type":"profile"}`, `{"_id":"user-2","type":"profile"}`)
+
+	concurrent := addVisibility
+	transform := concurrent.Transform
+	concurrent.Transform = func(doc map[string]interface{}) (bool, error) {
+		if doc["_id"] == "user-2" {
+			server.docs["user-2"]["_rev"] = "2-concurrent"
+		}
+		return transform(doc)
+	}
+
+	results, err := couchcandy.DB("lendr").Migrator().Run([]Migration{concurrent})
+	assert.EqualError(t, err, "migration 2024-01-profile-visibility: 1 documents were not written")
+	assert.Equal(t, 1, results[0].Written)
+	assert.Equal(t, "conflict", results[0].Failed[0].Error)
+	assert.JSONEq(t, `{"_id":"_local/migrations","applied":{}}`, server.record)
+
+	_, err = couchcandy.DB("lendr").Migrator().Run([]Migration{{ID: "no-transform"}})
+	assert.NotNil(t, err)
+
+}
@@ -0,0 +1,182 @@
+package couchcandy
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// LocalNode is the alias CouchDB resolves to the node receiving the request
+const LocalNode string = "_local"
+
+// Node Provides the configuration, statistics and maintenance calls of a
+// single node of the cluster.
+type Node struct {
+	handle
+	name string
+}
+
+// CouchDBConfig The commonly used keys of the couchdb configuration section.
+// CouchDB stores all configuration values as strings.
+type CouchDBConfig struct {
+	UUID                 string `json:"uuid,omitempty"`
+	DatabaseDir          string `json:"database_dir,omitempty"`
+	ViewIndexDir         string `json:"view_index_dir,omitempty"`
+	MaxDocumentSize      string `json:"max_document_size,omitempty"`
+	MaxDBsOpen           string `json:"max_dbs_open,omitempty"`
+	SingleNode           string `json:"single_node,omitempty"`
+	FileCompression      string `json:"file_compression,omitempty"`
+	AttachmentStreamSize string `json:"attachment_stream_buffer_size,omitempty"`
+}
+
+// CHTTPDConfig The commonly used keys of the chttpd configuration section.
+type CHTTPDConfig struct {
+	Port               string `json:"port,omitempty"`
+	BindAddress        string `json:"bind_address,omitempty"`
+	RequireValidUser   string `json:"require_valid_user,omitempty"`
+	MaxHTTPRequestSize string `json:"max_http_request_size,omitempty"`
+	EnableCORS         string `json:"enable_cors,omitempty"`
+	Backlog            string `json:"backlog,omitempty"`
+	ServerOptions      string `json:"server_options,omitempty"`
+}
+
+// LogConfig The keys of the log configuration section.
+type LogConfig struct {
+	Level       string `json:"level,omitempty"`
+	Writer      string `json:"writer,omitempty"`
+	File        string `json:"file,omitempty"`
+	WriteBuffer string `json:"write_buffer,omitempty"`
+	WriteDelay  string `json:"write_delay,omitempty"`
+}
+
+// SystemInfo The Erlang VM information returned by the _system endpoint.
+type SystemInfo struct {
+	Uptime            int64            `json:"uptime,omitempty"`
+	Memory            map[string]int64 `json:"memory,omitempty"`
+	RunQueue          int              `json:"run_queue,omitempty"`
+	EtsTableCount     int              `json:"ets_table_count,omitempty"`
+	ContextSwitches   int64            `json:"context_switches,omitempty"`
+	Reductions        int64            `json:"reductions,omitempty"`
+	GarbageCollection json.RawMessage  `json:"garbage_collection_count,omitempty"`
+	ProcessCount      int              `json:"process_count,omitempty"`
+	ProcessLimit      int              `json:"process_limit,omitempty"`
+	MessageQueues     json.RawMessage  `json:"message_queues,omitempty"`
+}
+
+// Node Returns the API of the named node. Use LocalNode for the node receiving
+// the requests or one of the names returned by Membership.
+func (s *Server) Node(name string) *Node {
+	return &Node{
+		handle: s.handle,
+		name:   name,
+	}
+}
+
+// Name Returns the name of the node.
+func (n *Node) Name() string {
+	return n.name
+}
+
+// Config Returns the whole configuration of the node, keyed by section.
+func (n *Node) Config() (map[string]map[string]string, error) {
+	config := make(map[string]map[string]string)
+	err := n.getJSON(n.url("_config"), &config)
+	return config, err
+}
+
+// ConfigSection Returns the keys and values of a configuration section.
+func (n *Node) ConfigSection(section string) (map[string]string, error) {
+	values := make(map[string]string)
+	err := n.getJSON(n.url("_config", section), &values)
+	return values, err
+}
+
+// ConfigValue Returns the value of a configuration key.
+func (n *Node) ConfigValue(section, key string) (string, error) {
+	var value string
+	err := n.getJSON(n.url("_config", section, key), &value)
+	return value, err
+}
+
+// SetConfigValue Changes the value of a configuration key and returns the
+// value it had before.
+func (n *Node) SetConfigValue(section, key, value string) (string, error) {
+
+	body, _ := json.Marshal(value)
+	page, err := n.putJSON(n.url("_config", section, key), string(body))
+	if err != nil {
+		return "", err
+	}
+
+	return toConfigValue(page)
+
+}
+
+// DeleteConfigValue Removes a configuration key and returns the value it had.
+func (n *Node) DeleteConfigValue(section, key string) (string, error) {
+
+	page, err := n.delete(n.url("_config", section, key))
+	if err != nil {
+		return "", err
+	}
+
+	return toConfigValue(page)
+
+}
+
+// CouchDBConfig Returns the couchdb section of the configuration.
+func (n *Node) CouchDBConfig() (*CouchDBConfig, error) {
+	config := &CouchDBConfig{}
+	err := n.getJSON(n.url("_config", "couchdb"), config)
+	return config, err
+}
+
+// CHTTPDConfig Returns the chttpd section of the configuration.
+func (n *Node) CHTTPDConfig() (*CHTTPDConfig, error) {
+	config := &CHTTPDConfig{}
+	err := n.getJSON(n.url("_config", "chttpd"), config)
+	return config, err
+}
+
+// LogConfig Returns the log section of the configuration.
+func (n *Node) LogConfig() (*LogConfig, error) {
+	config := &LogConfig{}
+	err := n.getJSON(n.url("_config", "log"), config)
+	return config, err
+}
+
+// Stats Returns the statistics of the node. The optional path narrows the
+// result down, for instance Stats("couchdb", "request_time"). Statistics are
+// returned as raw JSON since their shape depends on the metric.
+func (n *Node) Stats(path ...string) (json.RawMessage, error) {
+	var stats json.RawMessage
+	err := n.getJSON(n.url(append([]string{"_stats"}, path...)...), &stats)
+	return stats, err
+}
+
+// System Returns the Erlang VM information of the node.
+func (n *Node) System() (*SystemInfo, error) {
+	system := &SystemInfo{}
+	err := n.getJSON(n.url("_system"), system)
+	return system, err
+}
+
+// Restart Restarts the node. Requests to the node fail until it is back up.
+func (n *Node) Restart() (*OperationResponse, error) {
+	return n.postOperation(n.url("_restart"), "")
+}
+
+func (n *Node) url(segments ...string) string {
+	return fmt.Sprintf("%s/_node/%s/%s", createBaseURL(n.session), escapeSegment(n.name), joinSegments(segments...))
+}
+
+func toConfigValue(page []byte) (string, error) {
+
+	if couchError := toCouchError(page); couchError != nil {
+		return "", couchError
+	}
+
+	var value string
+	err := json.Unmarshal(page, &value)
+	return value, err
+
+}
@@ -0,0 +1,140 @@
+package couchcandy
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// CreateDatabaseOptions Options available when creating a database.
+// Q : number of shards, the server default when 0
+// N : number of replicas, the server default when 0
+// Partitioned : creates a partitioned database
+type CreateDatabaseOptions struct {
+	Q           int
+	N           int
+	Partitioned bool
+}
+
+// Partition A handle on a partition of a partitioned database. The queries of
+// a partition only read the shard holding it, which makes them much cheaper
+// than their global counterparts. Document ids of a partitioned database are
+// made of the partition name, a colon and the document key.
+type Partition struct {
+	db   *DB
+	name string
+}
+
+// PartitionInfo Information about a partition of a database.
+type PartitionInfo struct {
+	DBName      string `json:"db_name,omitempty"`
+	Partition   string `json:"partition,omitempty"`
+	DocCount    int    `json:"doc_count,omitempty"`
+	DocDelCount int    `json:"doc_del_count,omitempty"`
+	Sizes       Sizes  `json:"sizes,omitempty"`
+	Error       string `json:"error,omitempty"`
+	Reason      string `json:"reason,omitempty"`
+}
+
+// Sizes The active and external sizes of a database or partition.
+type Sizes struct {
+	Active   int `json:"active,omitempty"`
+	External int `json:"external,omitempty"`
+}
+
+// CreateDatabase Creates a database with the passed sharding and partitioning
+// options.
+func (s *Server) CreateDatabase(name string, options CreateDatabaseOptions) (*OperationResponse, error) {
+
+	url := fmt.Sprintf("%s%s", createDatabaseURL(withDatabase(s.session, name)), toCreateDatabaseQueryString(options))
+
+	page, err := s.putJSON(url, "")
+	if err != nil {
+		return nil, err
+	}
+
+	return toOperationResponse(page)
+
+}
+
+// Partition Returns a handle on the named partition of the database.
+func (db *DB) Partition(name string) *Partition {
+	return &Partition{db: db, name: name}
+}
+
+// Name Returns the name of the partition.
+func (p *Partition) Name() string {
+	return p.name
+}
+
+// PartitionInfo Returns the document count and sizes of the partition.
+func (p *Partition) PartitionInfo() (*PartitionInfo, error) {
+
+	page, err := p.db.get(p.url())
+	if err != nil {
+		return nil, err
+	}
+
+	info := &PartitionInfo{}
+	unmarshallError := json.Unmarshal(page, info)
+	return info, unmarshallError
+
+}
+
+// Documents Returns the documents of the partition based on the passed parameters.
+func (p *Partition) Documents(options Options) (*AllDocuments, error) {
+
+	url := fmt.Sprintf("%s/_all_docs%s", p.url(), toQueryString(options))
+	page, err := p.db.get(url)
+	if err != nil {
+		return nil, err
+	}
+
+	return toAllDocuments(page)
+
+}
+
+// View Calls the passed view on the documents of the partition.
+func (p *Partition) View(ddoc, view string, options Options) (*ViewResponse, error) {
+
+	url := fmt.Sprintf("%s/%s%s", p.url(), designDocPath(ddoc, "_view", view), toQueryString(options))
+	page, err := p.db.get(url)
+	if err != nil {
+		return nil, err
+	}
+	return toViewResponse(page)
+
+}
+
+// Find Runs the Mango query on the documents of the partition.
+func (p *Partition) Find(query FindQuery) (*FindResponse, error) {
+	return p.db.find(fmt.Sprintf("%s/_find", p.url()), query)
+}
+
+// Explain Tells which index and options the Mango query would use on the partition.
+func (p *Partition) Explain(query FindQuery) (*ExplainResponse, error) {
+	return p.db.explain(fmt.Sprintf("%s/_explain", p.url()), query)
+}
+
+func (p *Partition) url() string {
+	return fmt.Sprintf("%s/_partition/%s", createDatabaseURL(p.db.session), escapeSegment(p.name))
+}
+
+func toCreateDatabaseQueryString(options CreateDatabaseOptions) string {
+
+	parameters := make([]string, 0)
+	if options.Q != 0 {
+		parameters = append(parameters, fmt.Sprintf("q=%d", options.Q))
+	}
+	if options.N != 0 {
+		parameters = append(parameters, fmt.Sprintf("n=%d", options.N))
+	}
+	if options.Partitioned {
+		parameters = append(parameters, "partitioned=true")
+	}
+	if len(parameters) == 0 {
+		return ""
+	}
+	return "?" + strings.Join(parameters, "&")
+
+}
@@ -0,0 +1,57 @@
+package couchcandy
+
+import (
+	"net/url"
+	"strings"
+)
+
+// CouchDB decodes each segment of a path on its own, so an id or a name
+// holding a "/" must be sent as %2F to reach the right resource, and a "+"
+// as %2B not to be read as a space. The design and local documents are the
+// exception, their "_design/" or "_local/" prefix is part of the path.
+
+// escapeSegment escapes a value so that it is read as a single path segment.
+func escapeSegment(segment string) string {
+	return strings.ReplaceAll(url.PathEscape(segment), "+", "%2B")
+}
+
+// escapeDocID escapes a document id, keeping the "_design/" and "_local/"
+// prefixes as they are.
+func escapeDocID(id string) string {
+	for _, prefix := range []string{designPrefix, localPrefix} {
+		if strings.HasPrefix(id, prefix) {
+			return prefix + escapeSegment(strings.TrimPrefix(id, prefix))
+		}
+	}
+	return escapeSegment(id)
+}
+
+// escapeAttachmentName escapes an attachment name. CouchDB joins the
+// segments following the document id, so the slashes of the name are kept.
+func escapeAttachmentName(name string) string {
+	segments := strings.Split(name, "/")
+	for i, segment := range segments {
+		segments[i] = escapeSegment(segment)
+	}
+	return strings.Join(segments, "/")
+}
+
+// joinSegments escapes and joins path segments.
+func joinSegments(segments ...string) string {
+	escaped := make([]string, len(segments))
+	for i, segment := range segments {
+		escaped[i] = escapeSegment(segment)
+	}
+	return strings.Join(escaped, "/")
+}
+
+// designDocPath returns the path of the design document, relative to its
+// database, followed by the segments, like "_design/cards/_view/by_suit".
+// The ddoc is the name of the design document, with or without its prefix.
+func designDocPath(ddoc string, segments ...string) string {
+	path := escapeDocID(designPrefix + strings.TrimPrefix(ddoc, designPrefix))
+	if len(segments) == 0 {
+		return path
+	}
+	return path + "/" + joinSegments(segments...)
+}
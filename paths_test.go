@@ -0,0 +1,154 @@
+package couchcandy
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// awkwardIDs maps document ids to the escaped form CouchDB expects in a path.
+var awkwardIDs = map[string]string{
+	"card-1":                    "card-1",
+	"cards/spades/1":            "cards%2Fspades%2F1",
+	"what?":                     "what%3F",
+	"#hashtag":                  "%23hashtag",
+	"a+b":                       "a%2Bb",
+	"ace of spades":             "ace%20of%20spades",
+	"100%":                      "100%25",
+	"élan":                      "%C3%A9lan",
+	"org.couchdb.user:jan":      "org.couchdb.user:jan",
+	"_design/cards":             "_design/cards",
+	"_design/cards/v2":          "_design/cards%2Fv2",
+	"_design/a b":               "_design/a%20b",
+	"_local/checkpoint":         "_local/checkpoint",
+	"_local/repl/ication?id=1":  "_local/repl%2Fication%3Fid=1",
+	"_designer":                 "_designer",
+	"semi;colon,comma&amp=true": "semi%3Bcolon%2Ccomma&amp=true",
+}
+
+// requestedPath records the escaped path of the last request of the client.
+func requestedPath(couchcandy *CouchCandy) *string {
+	path := new(string)
+	couchcandy.Transport = TransportFunc(func(req *http.Request) (*http.Response, error) {
+		*path = req.URL.EscapedPath()
+		return statusResponse(http.StatusOK, `{"ok":true,"rows":[]}`), nil
+	})
+	return path
+}
+
+func TestEscapeDocID(t *testing.T) {
+
+	for id, expected := range awkwardIDs {
+		assert.Equal(t, expected, escapeDocID(id), id)
+	}
+
+}
+
+func TestDocumentPaths(t *testing.T) {
+
+	couchcandy := NewCouchCandy(Session{
+		Host: "http://127.0.0.1", Port: 5984, Database: "lendr", Username: "test", Password: "gotest",
+	})
+	path := requestedPath(couchcandy)
+
+	for id, expected := range awkwardIDs {
+
+		_ = couchcandy.Document(id, &CandyDocument{}, Options{})
+		assert.Equal(t, "/lendr/"+expected+"/", *path, id)
+
+		_, _ = couchcandy.AddWithID(id, map[string]string{})
+		assert.Equal(t, "/lendr/"+expected, *path, id)
+
+		_, _ = couchcandy.DeleteDocument(id, "1-abc")
+		assert.Equal(t, "/lendr/"+expected, *path, id)
+
+		_, _ = couchcandy.DB("lendr").Exists(id)
+		assert.Equal(t, "/lendr/"+expected, *path, id)
+
+		_, _ = couchcandy.AddAttachment(id, "1-abc", "photos/ace #1.png", "image/png", []byte{})
+		assert.Equal(t, "/lendr/"+expected+"/photos/ace%20%231.png", *path, id)
+
+	}
+
+}
+
+func TestLocalDocumentPaths(t *testing.T) {
+
+	couchcandy := NewCouchCandy(Session{
+		Host: "http://127.0.0.1", Port: 5984, Database: "lendr", Username: "test", Password: "gotest",
+	})
+	path := requestedPath(couchcandy)
+
+	_ = couchcandy.DB("lendr").LocalDocument("repl/ication", &CandyDocument{})
+	assert.Equal(t, "/lendr/_local/repl%2Fication", *path)
+
+	_, _ = couchcandy.DB("lendr").PutLocal("_local/a b", map[string]string{})
+	assert.Equal(t, "/lendr/_local/a%20b", *path)
+
+}
+
+func TestDesignDocumentPaths(t *testing.T) {
+
+	couchcandy := NewCouchCandy(Session{
+		Host: "http://127.0.0.1", Port: 5984, Database: "tenants/acme", Username: "test", Password: "gotest",
+	})
+	path := requestedPath(couchcandy)
+	db := couchcandy.DB("tenants/acme")
+
+	_, _ = db.View("cards", "by suit/color", Options{})
+	assert.Equal(t, "/tenants%2Facme/_design/cards/_view/by%20suit%2Fcolor", *path)
+
+	_, _ = db.View("_design/cards", "by_suit", Options{})
+	assert.Equal(t, "/tenants%2Facme/_design/cards/_view/by_suit", *path)
+
+	_, _ = db.ViewWithList("cards", "as+table", "by_suit", Options{})
+	assert.Equal(t, "/tenants%2Facme/_design/cards/_list/as%2Btable/cards/by_suit", *path)
+
+	_, _ = db.Show("cards", "card", "cards/1", nil)
+	assert.Equal(t, "/tenants%2Facme/_design/cards/_show/card/cards%2F1", *path)
+
+	_, _ = db.CallUpdateHandler("cards", "stamp", "_design/other", "")
+	assert.Equal(t, "/tenants%2Facme/_design/cards/_update/stamp/_design/other", *path)
+
+	_, _ = db.DesignDocInfo("my cards")
+	assert.Equal(t, "/tenants%2Facme/_design/my%20cards/_info", *path)
+
+	_, _ = db.CompactViews("_design/cards")
+	assert.Equal(t, "/tenants%2Facme/_compact/cards", *path)
+
+	_, _ = db.Partition("sensor/1").View("cards", "by_suit", Options{})
+	assert.Equal(t, "/tenants%2Facme/_partition/sensor%2F1/_design/cards/_view/by_suit", *path)
+
+}
+
+func TestCopyDestinationEscaping(t *testing.T) {
+
+	couchcandy := NewCouchCandy(Session{
+		Host: "http://127.0.0.1", Port: 5984, Database: "lendr", Username: "test", Password: "gotest",
+	})
+	var destination string
+	couchcandy.Transport = TransportFunc(func(req *http.Request) (*http.Response, error) {
+		destination = req.Header.Get(HeaderDestination)
+		return statusResponse(http.StatusCreated, `{"ok":true}`), nil
+	})
+
+	_, err := couchcandy.DB("lendr").Copy("card-1", "cards/ace of spades", "1-abc")
+	assert.Nil(t, err)
+	assert.Equal(t, "cards%2Face%20of%20spades?rev=1-abc", destination)
+
+}
+
+func TestNodePaths(t *testing.T) {
+
+	couchcandy := NewCouchCandy(Session{
+		Host: "http://127.0.0.1", Port: 5984, Database: "lendr", Username: "test", Password: "gotest",
+	})
+	path := requestedPath(couchcandy)
+
+	_, _ = couchcandy.Server().Node("couchdb@node one").ConfigValue("log", "level")
+	assert.Equal(t, "/_node/couchdb@node%20one/_config/log/level", *path)
+	assert.False(t, strings.Contains(*path, " "))
+
+}
@@ -0,0 +1,68 @@
+package couchcandy
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// ReplicationRequest The body of a _replicate request. Source and Target are
+// database urls, or database names on the server of the session.
+type ReplicationRequest struct {
+	Source       string      `json:"source"`
+	Target       string      `json:"target"`
+	CreateTarget bool        `json:"create_target,omitempty"`
+	Continuous   bool        `json:"continuous,omitempty"`
+	Cancel       bool        `json:"cancel,omitempty"`
+	DocIDs       []string    `json:"doc_ids,omitempty"`
+	Filter       string      `json:"filter,omitempty"`
+	Selector     interface{} `json:"selector,omitempty"`
+}
+
+// ReplicationResponse The outcome of a replication. A one-shot replication
+// reports its history, a continuous one the id of its checkpoint.
+type ReplicationResponse struct {
+	OK            bool              `json:"ok"`
+	SessionID     string            `json:"session_id,omitempty"`
+	SourceLastSeq string            `json:"source_last_seq,omitempty"`
+	LocalID       string            `json:"_local_id,omitempty"`
+	History       []json.RawMessage `json:"history,omitempty"`
+	NoChanges     bool              `json:"no_changes,omitempty"`
+}
+
+// Replicate Replicates the source database into the target one. A one-shot
+// replication returns once it is done, a continuous one as soon as it is
+// started, and is stopped by the same request with Cancel set.
+func (s *Server) Replicate(replication ReplicationRequest) (*ReplicationResponse, error) {
+
+	replication.Source = s.replicationEndpoint(replication.Source)
+	replication.Target = s.replicationEndpoint(replication.Target)
+
+	body, err := json.Marshal(replication)
+	if err != nil {
+		return nil, err
+	}
+
+	page, err := s.postJSON(fmt.Sprintf("%s/_replicate", createBaseURL(s.session)), string(body))
+	if err != nil {
+		return nil, err
+	}
+
+	if couchError := toCouchError(page); couchError != nil {
+		return nil, couchError
+	}
+
+	response := &ReplicationResponse{}
+	unmarshallError := json.Unmarshal(page, response)
+	return response, unmarshallError
+
+}
+
+// replicationEndpoint turns a database name into its url on the server,
+// CouchDB only replicating between urls.
+func (s *Server) replicationEndpoint(database string) string {
+	if strings.Contains(database, "://") {
+		return database
+	}
+	return createDatabaseURL(withDatabase(s.session, database))
+}
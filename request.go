@@ -0,0 +1,199 @@
+package couchcandy
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// Request A request to CouchDB. Every call of the client is built as a
+// Request and sent through the middlewares of the client to its transport,
+// so the same pipeline serves documents, views, HEAD, COPY or feeds alike.
+//
+//	res, err := client.NewRequest(http.MethodGet, url).
+//	  WithContext(ctx).
+//	  Header("Accept", JSONContentType).
+//	  Query("conflicts", "true").
+//	  Raw()
+type Request struct {
+	client *CouchCandy
+	ctx    context.Context
+	method string
+	url    string
+	header http.Header
+	query  url.Values
+	body   []byte
+	stream bool
+	// prefix and timeout come from the session the request is built for,
+	// the one of the handle for the calls of a DB or Server.
+	prefix  string
+	timeout time.Duration
+}
+
+// NewRequest Starts a request with the passed method and url, bound to the
+// current session of the client.
+func (c *CouchCandy) NewRequest(method, url string) *Request {
+	return &Request{
+		client:  c,
+		ctx:     context.Background(),
+		method:  method,
+		url:     url,
+		header:  http.Header{},
+		prefix:  c.Session.PathPrefix,
+		timeout: c.Session.Timeout,
+	}
+}
+
+// WithContext Sets the context of the request, used for cancellation.
+func (r *Request) WithContext(ctx context.Context) *Request {
+	r.ctx = ctx
+	return r
+}
+
+// Stream Marks the request as a feed, which the Timeout of the session
+// does not bound. Its lifetime is only bound by its context.
+func (r *Request) Stream() *Request {
+	r.stream = true
+	return r
+}
+
+// Header Adds a header to the request.
+func (r *Request) Header(key, value string) *Request {
+	r.header.Add(key, value)
+	return r
+}
+
+// Query Adds a query parameter to the request. The parameter is appended to
+// the ones already present in the url.
+func (r *Request) Query(key, value string) *Request {
+	if r.query == nil {
+		r.query = url.Values{}
+	}
+	r.query.Add(key, value)
+	return r
+}
+
+// Body Sets the body of the request along with its content type.
+func (r *Request) Body(contentType string, body []byte) *Request {
+	r.header.Set(HeaderContentType, contentType)
+	r.body = body
+	return r
+}
+
+// JSON Sets a JSON body on the request.
+func (r *Request) JSON(body string) *Request {
+	return r.Body(JSONContentType, []byte(body))
+}
+
+// HTTPRequest Returns the http request corresponding to the Request.
+func (r *Request) HTTPRequest() (*http.Request, error) {
+	return r.build(r.ctx)
+}
+
+func (r *Request) build(ctx context.Context) (*http.Request, error) {
+
+	if r.prefix != "" {
+		ctx = context.WithValue(ctx, pathPrefixKey{}, r.prefix)
+	}
+
+	target := r.url
+	if len(r.query) > 0 {
+		separator := "?"
+		if strings.Contains(target, "?") {
+			separator = "&"
+		}
+		target = target + separator + r.query.Encode()
+	}
+
+	var body io.Reader
+	if r.body != nil {
+		body = bytes.NewReader(r.body)
+	}
+
+	request, err := http.NewRequestWithContext(ctx, r.method, target, body)
+	if err != nil {
+		return nil, err
+	}
+
+	for key, values := range r.header {
+		for _, value := range values {
+			request.Header.Add(key, value)
+		}
+	}
+
+	return request, nil
+
+}
+
+// Do Sends the request through the middlewares and returns the response.
+// The caller is responsible for closing its body.
+func (r *Request) Do() (*http.Response, error) {
+
+	if r.timeout <= 0 || r.stream {
+		request, err := r.HTTPRequest()
+		if err != nil {
+			return nil, err
+		}
+		return r.client.transport().Do(request)
+	}
+
+	ctx, cancel := context.WithTimeout(r.ctx, r.timeout)
+	request, err := r.build(ctx)
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+
+	res, err := r.client.transport().Do(request)
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	if res.Body == nil {
+		cancel()
+		return res, nil
+	}
+	res.Body = &cancelReadCloser{ReadCloser: res.Body, cancel: cancel}
+	return res, nil
+
+}
+
+// cancelReadCloser releases the timeout of a request when its body is closed.
+type cancelReadCloser struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+func (c *cancelReadCloser) Close() error {
+	err := c.ReadCloser.Close()
+	c.cancel()
+	return err
+}
+
+// Read Sends the request and returns the whole body of the response.
+func (r *Request) Read() ([]byte, error) {
+
+	res, err := r.Do()
+	if err != nil {
+		return nil, err
+	}
+
+	page, err := ioutil.ReadAll(res.Body)
+	defer res.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+
+	return page, nil
+
+}
+
+// Raw Sends the request and returns the whole response without interpreting it.
+func (r *Request) Raw() (*RawResponse, error) {
+	return toRawResponse(r.Do())
+}
@@ -0,0 +1,77 @@
+package couchcandy
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRequestBuilder(t *testing.T) {
+
+	var received *http.Request
+	var body string
+	couchcandy := &CouchCandy{Transport: TransportFunc(func(req *http.Request) (*http.Response, error) {
+		received = req
+		page, _ := ioutil.ReadAll(req.Body)
+		body = string(page)
+		return &http.Response{
+			StatusCode: http.StatusCreated,
+			Header:     http.Header{HeaderContentType: []string{JSONContentType}},
+			Body:       ioutil.NopCloser(bytes.NewBufferString(`{"ok":true}`)),
+		}, nil
+	})}
+
+	type key struct{}
+	ctx := context.WithValue(context.Background(), key{}, "value")
+	raw, err := couchcandy.NewRequest(http.MethodPut, "http://127.0.0.1:5984/lendr/card-1?batch=ok").
+		WithContext(ctx).
+		Header("X-Test", "yes").
+		Query("new_edits", "false").
+		JSON(`{"suit":"spades"}`).
+		Raw()
+
+	assert.Nil(t, err)
+	assert.Equal(t, http.StatusCreated, raw.StatusCode)
+	assert.Equal(t, JSONContentType, raw.ContentType)
+	assert.Equal(t, `{"ok":true}`, string(raw.Body))
+
+	assert.Equal(t, http.MethodPut, received.Method)
+	assert.Equal(t, "http://127.0.0.1:5984/lendr/card-1?batch=ok&new_edits=false", received.URL.String())
+	assert.Equal(t, "yes", received.Header.Get("X-Test"))
+	assert.Equal(t, JSONContentType, received.Header.Get(HeaderContentType))
+	assert.Equal(t, "value", received.Context().Value(key{}))
+	assert.Equal(t, `{"suit":"spades"}`, body)
+
+}
+
+func TestRequestQueryWithoutQueryString(t *testing.T) {
+
+	request, err := (&CouchCandy{}).NewRequest(http.MethodGet, "http://127.0.0.1:5984/lendr/_all_docs").
+		Query("limit", "10").
+		HTTPRequest()
+
+	assert.Nil(t, err)
+	assert.Equal(t, "limit=10", request.URL.RawQuery)
+	assert.Nil(t, request.Body)
+
+}
+
+func TestRequestRead(t *testing.T) {
+
+	couchcandy := &CouchCandy{Transport: &MockRunningHTTPClient{}}
+	page, err := couchcandy.NewRequest(http.MethodGet, "http://127.0.0.1:5984/lendr/_all_docs").Read()
+	assert.Nil(t, err)
+
+	allDocuments, err := toAllDocuments(page)
+	assert.Nil(t, err)
+	assert.Len(t, allDocuments.Rows, 5)
+
+	couchcandy.Transport = &MockFailingHTTPClient{}
+	_, err = couchcandy.NewRequest(http.MethodGet, "http://127.0.0.1:5984/lendr/_all_docs").Read()
+	assert.NotNil(t, err)
+
+}
@@ -0,0 +1,220 @@
+package couchcandy
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// Security The security object of a database. Admins can manage design documents
+// and the security object itself, members can read and write regular documents.
+// Keys CouchDB or other tools may have added to the object are kept as is.
+type Security struct {
+	Admins  SecurityGroup `json:"admins"`
+	Members SecurityGroup `json:"members"`
+	extra   map[string]json.RawMessage
+}
+
+// SecurityGroup The names and roles of a section of the security object.
+type SecurityGroup struct {
+	Names []string `json:"names"`
+	Roles []string `json:"roles"`
+}
+
+// GetSecurity Returns the security object of the database.
+func (db *DB) GetSecurity() (*Security, error) {
+
+	url := fmt.Sprintf("%s/_security", createDatabaseURL(db.session))
+	page, err := db.get(url)
+	if err != nil {
+		return nil, err
+	}
+
+	if couchError := toCouchError(page); couchError != nil {
+		return nil, couchError
+	}
+
+	security := &Security{}
+	unmarshallError := json.Unmarshal(page, security)
+	return security, unmarshallError
+
+}
+
+// SetSecurity Replaces the security object of the database.
+func (db *DB) SetSecurity(security *Security) (*OperationResponse, error) {
+
+	url := fmt.Sprintf("%s/_security", createDatabaseURL(db.session))
+	body, marshallError := json.Marshal(security)
+	if marshallError != nil {
+		return nil, marshallError
+	}
+
+	return db.putOperation(url, string(body))
+
+}
+
+// AddAdminName Adds the user name to the admins of the database.
+func (db *DB) AddAdminName(user string) (*OperationResponse, error) {
+	return db.updateSecurity(func(s *Security) bool { return s.Admins.addName(user) })
+}
+
+// RemoveAdminName Removes the user name from the admins of the database.
+func (db *DB) RemoveAdminName(user string) (*OperationResponse, error) {
+	return db.updateSecurity(func(s *Security) bool { return s.Admins.removeName(user) })
+}
+
+// AddAdminRole Adds the role to the admins of the database.
+func (db *DB) AddAdminRole(role string) (*OperationResponse, error) {
+	return db.updateSecurity(func(s *Security) bool { return s.Admins.addRole(role) })
+}
+
+// RemoveAdminRole Removes the role from the admins of the database.
+func (db *DB) RemoveAdminRole(role string) (*OperationResponse, error) {
+	return db.updateSecurity(func(s *Security) bool { return s.Admins.removeRole(role) })
+}
+
+// AddMemberName Adds the user name to the members of the database.
+func (db *DB) AddMemberName(user string) (*OperationResponse, error) {
+	return db.updateSecurity(func(s *Security) bool { return s.Members.addName(user) })
+}
+
+// RemoveMemberName Removes the user name from the members of the database.
+func (db *DB) RemoveMemberName(user string) (*OperationResponse, error) {
+	return db.updateSecurity(func(s *Security) bool { return s.Members.removeName(user) })
+}
+
+// AddMemberRole Adds the role to the members of the database.
+func (db *DB) AddMemberRole(role string) (*OperationResponse, error) {
+	return db.updateSecurity(func(s *Security) bool { return s.Members.addRole(role) })
+}
+
+// RemoveMemberRole Removes the role from the members of the database.
+func (db *DB) RemoveMemberRole(role string) (*OperationResponse, error) {
+	return db.updateSecurity(func(s *Security) bool { return s.Members.removeRole(role) })
+}
+
+// securityAttempts bounds the writes of updateSecurity when concurrent
+// writers keep overwriting its change.
+const securityAttempts = 5
+
+// updateSecurity reads the current security object, applies the change and
+// writes it back only when the change modified it. Reading first makes sure
+// the names and roles managed by others are left untouched. The security
+// object has no revision, so a concurrent writer can overwrite the change :
+// the object is read again after the write, and the change applied again as
+// long as it is missing.
+func (db *DB) updateSecurity(change func(*Security) bool) (*OperationResponse, error) {
+
+	response := &OperationResponse{OK: true}
+	for attempt := 0; attempt <= securityAttempts; attempt++ {
+
+		security, err := db.GetSecurity()
+		if err != nil {
+			return nil, err
+		}
+
+		// The change is there, written by this call or by another one.
+		if !change(security) {
+			return response, nil
+		}
+		if attempt == securityAttempts {
+			break
+		}
+
+		if response, err = db.SetSecurity(security); err != nil || !response.OK {
+			return response, err
+		}
+
+	}
+
+	return response, fmt.Errorf("_security: the change was overwritten by concurrent writers %d times", securityAttempts)
+
+}
+
+// MarshalJSON writes the admins and members along with any other key read
+// from CouchDB. Empty sections are written as empty arrays since CouchDB
+// rejects null names and roles.
+func (s Security) MarshalJSON() ([]byte, error) {
+
+	object := make(map[string]interface{}, len(s.extra)+2)
+	for key, value := range s.extra {
+		object[key] = value
+	}
+	object["admins"] = s.Admins.normalized()
+	object["members"] = s.Members.normalized()
+	return json.Marshal(object)
+
+}
+
+// UnmarshalJSON reads the admins and members and keeps the other keys.
+func (s *Security) UnmarshalJSON(data []byte) error {
+
+	object := make(map[string]json.RawMessage)
+	if err := json.Unmarshal(data, &object); err != nil {
+		return err
+	}
+
+	if admins, ok := object["admins"]; ok {
+		if err := json.Unmarshal(admins, &s.Admins); err != nil {
+			return err
+		}
+		delete(object, "admins")
+	}
+	if members, ok := object["members"]; ok {
+		if err := json.Unmarshal(members, &s.Members); err != nil {
+			return err
+		}
+		delete(object, "members")
+	}
+
+	s.extra = object
+	return nil
+
+}
+
+func (g SecurityGroup) normalized() SecurityGroup {
+	if g.Names == nil {
+		g.Names = make([]string, 0)
+	}
+	if g.Roles == nil {
+		g.Roles = make([]string, 0)
+	}
+	return g
+}
+
+func (g *SecurityGroup) addName(name string) bool {
+	return addUnique(&g.Names, name)
+}
+
+func (g *SecurityGroup) removeName(name string) bool {
+	return remove(&g.Names, name)
+}
+
+func (g *SecurityGroup) addRole(role string) bool {
+	return addUnique(&g.Roles, role)
+}
+
+func (g *SecurityGroup) removeRole(role string) bool {
+	return remove(&g.Roles, role)
+}
+
+func addUnique(values *[]string, value string) bool {
+	for _, v := range *values {
+		if v == value {
+			return false
+		}
+	}
+	*values = append(*values, value)
+	return true
+}
+
+func remove(values *[]string, value string) bool {
+	kept := make([]string, 0, len(*values))
+	for _, v := range *values {
+		if v != value {
+			kept = append(kept, v)
+		}
+	}
+	removed := len(kept) != len(*values)
+	*values = kept
+	return removed
+}
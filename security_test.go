@@ -0,0 +1,177 @@
+package couchcandy
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetSecurity(t *testing.T) {
+
+	couchcandy := NewCouchCandy(Session{
+		Host: "http://127.0.0.1", Port: 5984, Database: "lendr", Username: "test", Password: "gotest",
+	})
+	mock(couchcandy).Get = func(string) (*http.Response, error) {
+		response := &http.Response{
+			Body: ioutil.NopCloser(bytes.NewBufferString(`{"admins":{"names":["superuser"],"roles":["admins"]},"members":{"names":["user1","user2"],"roles":["developers"]}}`)),
+		}
+		return response, nil
+	}
+
+	security, err := couchcandy.GetSecurity("lendr")
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"superuser"}, security.Admins.Names)
+	assert.Equal(t, []string{"developers"}, security.Members.Roles)
+
+}
+
+func TestGetSecurityError(t *testing.T) {
+
+	couchcandy := NewCouchCandy(Session{
+		Host: "http://127.0.0.1", Port: 5984, Database: "lendr", Username: "test", Password: "gotest",
+	})
+	mock(couchcandy).Get = func(string) (*http.Response, error) {
+		response := &http.Response{
+			Body: ioutil.NopCloser(bytes.NewBufferString(`{"error":"unauthorized","reason":"You are not a db or server admin."}`)),
+		}
+		return response, nil
+	}
+
+	_, err := couchcandy.GetSecurity("lendr")
+	assert.NotNil(t, err)
+
+}
+
+func TestSecurityMarshalKeepsUnknownKeys(t *testing.T) {
+
+	security := &Security{}
+	err := json.Unmarshal([]byte(`{"members":{"names":["bob"]},"couchdb_auth_only":true}`), security)
+	assert.Nil(t, err)
+
+	body, err := json.Marshal(security)
+	assert.Nil(t, err)
+	assert.JSONEq(t, `{"admins":{"names":[],"roles":[]},"members":{"names":["bob"],"roles":[]},"couchdb_auth_only":true}`, string(body))
+
+}
+
+func TestAddMemberRole(t *testing.T) {
+
+	couchcandy := NewCouchCandy(Session{
+		Host: "http://127.0.0.1", Port: 5984, Database: "lendr", Username: "test", Password: "gotest",
+	})
+	stored := `{"admins":{"names":["superuser"],"roles":[]},"members":{"names":[],"roles":["developers"]}}`
+	mock(couchcandy).Get = func(string) (*http.Response, error) {
+		response := &http.Response{
+			Body: ioutil.NopCloser(bytes.NewBufferString(stored)),
+		}
+		return response, nil
+	}
+	var sentBody string
+	mock(couchcandy).PutJSON = func(url, body string) (*http.Response, error) {
+		sentBody = body
+		stored = body
+		response := &http.Response{
+			Body: ioutil.NopCloser(bytes.NewBufferString(`{"ok":true}`)),
+		}
+		return response, nil
+	}
+
+	response, err := couchcandy.AddMemberRole("lendr", "testers")
+	assert.Nil(t, err)
+	assert.True(t, response.OK)
+	assert.JSONEq(t, `{"admins":{"names":["superuser"],"roles":[]},"members":{"names":[],"roles":["developers","testers"]}}`, sentBody)
+
+	// Adding an existing role does not write the security object again.
+	sentBody = ""
+	response, err = couchcandy.AddMemberRole("lendr", "developers")
+	assert.Nil(t, err)
+	assert.True(t, response.OK)
+	assert.Empty(t, sentBody)
+
+}
+
+func TestRemoveAdminName(t *testing.T) {
+
+	couchcandy := NewCouchCandy(Session{
+		Host: "http://127.0.0.1", Port: 5984, Database: "lendr", Username: "test", Password: "gotest",
+	})
+	stored := `{"admins":{"names":["superuser","olduser"],"roles":[]},"members":{"names":["user1"],"roles":[]}}`
+	mock(couchcandy).Get = func(string) (*http.Response, error) {
+		response := &http.Response{
+			Body: ioutil.NopCloser(bytes.NewBufferString(stored)),
+		}
+		return response, nil
+	}
+	var sentBody string
+	mock(couchcandy).PutJSON = func(url, body string) (*http.Response, error) {
+		sentBody = body
+		stored = body
+		response := &http.Response{
+			Body: ioutil.NopCloser(bytes.NewBufferString(`{"ok":true}`)),
+		}
+		return response, nil
+	}
+
+	_, err := couchcandy.RemoveAdminName("lendr", "olduser")
+	assert.Nil(t, err)
+	assert.JSONEq(t, `{"admins":{"names":["superuser"],"roles":[]},"members":{"names":["user1"],"roles":[]}}`, sentBody)
+
+}
+
+func TestUpdateSecurityConcurrentWriter(t *testing.T) {
+
+	couchcandy := NewCouchCandy(Session{
+		Host: "http://127.0.0.1", Port: 5984, Database: "lendr", Username: "test", Password: "gotest",
+	})
+	stored := `{"admins":{"names":[],"roles":[]},"members":{"names":["user1"],"roles":[]}}`
+	mock(couchcandy).Get = func(string) (*http.Response, error) {
+		return statusResponse(http.StatusOK, stored), nil
+	}
+	writes := make([]string, 0)
+	mock(couchcandy).PutJSON = func(url, body string) (*http.Response, error) {
+		writes = append(writes, body)
+		// The first write is overwritten by another writer adding user2.
+		if len(writes) == 1 {
+			stored = `{"admins":{"names":[],"roles":[]},"members":{"names":["user1","user2"],"roles":[]}}`
+		} else {
+			stored = body
+		}
+		return statusResponse(http.StatusOK, `{"ok":true}`), nil
+	}
+
+	response, err := couchcandy.DB("lendr").AddMemberName("user3")
+	assert.Nil(t, err)
+	assert.True(t, response.OK)
+	assert.Len(t, writes, 2)
+	assert.JSONEq(t, `{"admins":{"names":[],"roles":[]},"members":{"names":["user1","user2","user3"],"roles":[]}}`, stored)
+
+	mock(couchcandy).PutJSON = func(url, body string) (*http.Response, error) {
+		return statusResponse(http.StatusOK, `{"ok":true}`), nil
+	}
+	_, err = couchcandy.DB("lendr").AddMemberName("user4")
+	assert.NotNil(t, err)
+
+}
+
+func TestUpdateSecurityFailure(t *testing.T) {
+
+	couchcandy := NewCouchCandy(Session{
+		Host: "http://127.0.0.1", Port: 5984, Database: "lendr", Username: "test", Password: "gotest",
+	})
+	mock(couchcandy).Get = func(string) (*http.Response, error) {
+		return nil, fmt.Errorf("Deliberate error from TestUpdateSecurityFailure()")
+	}
+	mock(couchcandy).PutJSON = func(string, string) (*http.Response, error) {
+		t.Error("The security object should not be written when it cannot be read")
+		return nil, nil
+	}
+
+	_, err := couchcandy.AddAdminRole("lendr", "admins")
+	assert.NotNil(t, err)
+
+}
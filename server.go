@@ -0,0 +1,156 @@
+package couchcandy
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+)
+
+// Server Provides the calls that are not tied to a database, like listing,
+// creating or deleting databases.
+type Server struct {
+	handle
+}
+
+// Server Returns the server level API of the client.
+func (c *CouchCandy) Server() *Server {
+	return &Server{handle{
+		client:  c,
+		session: c.Session,
+	}}
+}
+
+// WithContext Returns a copy of the server API whose calls, and the ones of
+// its nodes, are sent with the context.
+func (s *Server) WithContext(ctx context.Context) *Server {
+	return &Server{s.handle.withContext(ctx)}
+}
+
+// AllDatabases : Returns all the database names in the system.
+func (s *Server) AllDatabases() ([]string, error) {
+
+	url := createAllDatabasesURL(s.session)
+	page, err := s.get(url)
+	if err != nil {
+		return nil, err
+	}
+
+	var dbs []string
+	unmarshallError := json.Unmarshal(page, &dbs)
+	return dbs, unmarshallError
+
+}
+
+// AllDatabasesWithOptions Returns the database names in the system based on the
+// passed parameters. Only Descending, Limit, Skip, StartKey and EndKey apply,
+// keys being JSON strings like for the other queries.
+func (s *Server) AllDatabasesWithOptions(options Options) ([]string, error) {
+
+	url := fmt.Sprintf("%s%s", createAllDatabasesURL(s.session), toAllDatabasesQueryString(options))
+	page, err := s.get(url)
+	if err != nil {
+		return nil, err
+	}
+
+	if couchError := toCouchError(page); couchError != nil {
+		return nil, couchError
+	}
+
+	var dbs []string
+	unmarshallError := json.Unmarshal(page, &dbs)
+	return dbs, unmarshallError
+
+}
+
+// ServerInfo Returns the welcome message of the server, with its version,
+// vendor and enabled features.
+func (s *Server) ServerInfo() (*ServerInfo, error) {
+
+	info := &ServerInfo{}
+	err := s.getJSON(createBaseURL(s.session), info)
+	return info, err
+
+}
+
+// Up Checks the health of the node. The returned status is "ok" when the node
+// is up and "maintenance_mode" or "nolb" when it should not receive traffic.
+func (s *Server) Up() (*UpStatus, error) {
+
+	status := &UpStatus{}
+	err := s.getJSON(fmt.Sprintf("%s/_up", createBaseURL(s.session)), status)
+	return status, err
+
+}
+
+// UUIDs Returns count universally unique identifiers generated by the server.
+func (s *Server) UUIDs(count int) ([]string, error) {
+
+	uuids := &struct {
+		UUIDs []string `json:"uuids"`
+	}{}
+	err := s.getJSON(fmt.Sprintf("%s/_uuids?count=%d", createBaseURL(s.session), count), uuids)
+	return uuids.UUIDs, err
+
+}
+
+// ActiveTasks Returns the tasks currently running on the server, such as
+// indexing, replication or compaction, along with their progress.
+func (s *Server) ActiveTasks() ([]ActiveTask, error) {
+
+	tasks := make([]ActiveTask, 0)
+	err := s.getJSON(fmt.Sprintf("%s/_active_tasks", createBaseURL(s.session)), &tasks)
+	return tasks, err
+
+}
+
+// Membership Returns the nodes known to the node and the nodes that are part
+// of the cluster.
+func (s *Server) Membership() (*Membership, error) {
+
+	membership := &Membership{}
+	err := s.getJSON(fmt.Sprintf("%s/_membership", createBaseURL(s.session)), membership)
+	return membership, err
+
+}
+
+// DBsInfo Returns the information of the named databases in a single request.
+// Databases that do not exist are reported with an error in their result.
+func (s *Server) DBsInfo(names []string) ([]DatabaseInfoResult, error) {
+
+	url := fmt.Sprintf("%s/_dbs_info", createBaseURL(s.session))
+	body, _ := json.Marshal(&AllDocumentsKeys{
+		Keys: names,
+	})
+
+	page, err := s.postJSON(url, string(body))
+	if err != nil {
+		return nil, err
+	}
+
+	if couchError := toCouchError(page); couchError != nil {
+		return nil, couchError
+	}
+
+	results := make([]DatabaseInfoResult, 0, len(names))
+	unmarshallError := json.Unmarshal(page, &results)
+	return results, unmarshallError
+
+}
+
+// AddDatabase : Creates a database in CouchDB
+func (s *Server) AddDatabase(name string) (*OperationResponse, error) {
+	return s.CreateDatabase(name, CreateDatabaseOptions{})
+}
+
+// DeleteDatabase : Deletes the passed database from the system.
+func (s *Server) DeleteDatabase(name string) (*OperationResponse, error) {
+
+	url := createDatabaseURL(withDatabase(s.session, name))
+	page, err := s.delete(url)
+	if err != nil {
+		return nil, err
+	}
+
+	return toOperationResponse(page)
+
+}
@@ -0,0 +1,216 @@
+package couchcandy
+
+// The methods below are shorthands on the client. The document and view methods
+// operate on the database of the session, as c.DB(c.Session.Database) would,
+// the administration and security methods on the named database and the
+// database management methods on c.Server(). None of them changes the session.
+
+// DatabaseInfo returns basic information about the database in session.
+func (c *CouchCandy) DatabaseInfo() (*DatabaseInfo, error) {
+	return c.sessionDB().DatabaseInfo()
+}
+
+// Document Returns the specified document.
+func (c *CouchCandy) Document(id string, v interface{}, options Options) error {
+	return c.sessionDB().Document(id, v, options)
+}
+
+// Add Adds a document in the database but the system will generate
+// an id. Look at AddWithID for setting an id for the document explicitly.
+func (c *CouchCandy) Add(document interface{}) (*OperationResponse, error) {
+	return c.sessionDB().Add(document)
+}
+
+// Update Updates a document in the database. Note that _id and _rev
+// fields are required in the passed document.
+func (c *CouchCandy) Update(document interface{}) (*OperationResponse, error) {
+	return c.sessionDB().Update(document)
+}
+
+// AddWithID Inserts a document in the database with the specified id
+func (c *CouchCandy) AddWithID(id string, document interface{}) (*OperationResponse, error) {
+	return c.sessionDB().AddWithID(id, document)
+}
+
+// AddAttachment adds the provided attachment to the specified
+// document and revision.
+func (c *CouchCandy) AddAttachment(id, rev, name, contentType string, file []byte) (*OperationResponse, error) {
+	return c.sessionDB().AddAttachment(id, rev, name, contentType, file)
+}
+
+// DeleteAttachment deletes the named attachment on the document corresponding
+// to the id-rev pair.
+func (c *CouchCandy) DeleteAttachment(id, rev, name string) (*OperationResponse, error) {
+	return c.sessionDB().DeleteAttachment(id, rev, name)
+}
+
+// Documents : Returns all documents in the database based on the passed parameters.
+func (c *CouchCandy) Documents(options Options) (*AllDocuments, error) {
+	return c.sessionDB().Documents(options)
+}
+
+// DesignDocs Returns the design documents of the database in session.
+func (c *CouchCandy) DesignDocs() ([]*DesignDocument, error) {
+	return c.sessionDB().DesignDocs()
+}
+
+// DocumentsByKeys Fetches all the documents corresponding to the passed keys array.
+func (c *CouchCandy) DocumentsByKeys(keys []string, options Options) (*AllDocuments, error) {
+	return c.sessionDB().DocumentsByKeys(keys, options)
+}
+
+// DeleteDocument Deletes the passed document with revision from the database
+func (c *CouchCandy) DeleteDocument(id string, revision string) (*OperationResponse, error) {
+	return c.sessionDB().DeleteDocument(id, revision)
+}
+
+// ChangeNotifications : Return the current change notifications.
+func (c *CouchCandy) ChangeNotifications(options Options) (*Changes, error) {
+	return c.sessionDB().ChangeNotifications(options)
+}
+
+// View : Calls the passed view with provided options
+func (c *CouchCandy) View(ddoc, view string, options Options) (*ViewResponse, error) {
+	return c.sessionDB().View(ddoc, view, options)
+}
+
+// ViewWithList calls the passed view with list and options
+func (c *CouchCandy) ViewWithList(ddoc, list, view string, options Options) (*RawResponse, error) {
+	return c.sessionDB().ViewWithList(ddoc, list, view, options)
+}
+
+// LocalDocument Returns the specified _local document of the database in session.
+func (c *CouchCandy) LocalDocument(id string, v interface{}) error {
+	return c.sessionDB().LocalDocument(id, v)
+}
+
+// PutLocal Creates or updates the specified _local document of the database in session.
+func (c *CouchCandy) PutLocal(id string, document interface{}) (*OperationResponse, error) {
+	return c.sessionDB().PutLocal(id, document)
+}
+
+// DeleteLocal Deletes the specified _local document of the database in session.
+func (c *CouchCandy) DeleteLocal(id, revision string) (*OperationResponse, error) {
+	return c.sessionDB().DeleteLocal(id, revision)
+}
+
+// LocalDocs Returns the _local documents of the database in session.
+func (c *CouchCandy) LocalDocs(options Options) (*AllDocuments, error) {
+	return c.sessionDB().LocalDocs(options)
+}
+
+// Compact Starts the compaction of the named database.
+func (c *CouchCandy) Compact(name string) (*OperationResponse, error) {
+	return c.DB(name).Compact()
+}
+
+// CompactViews Starts the compaction of the view indexes of the design
+// document in the named database.
+func (c *CouchCandy) CompactViews(name, ddoc string) (*OperationResponse, error) {
+	return c.DB(name).CompactViews(ddoc)
+}
+
+// ViewCleanup Removes the unused view index files of the named database.
+func (c *CouchCandy) ViewCleanup(name string) (*OperationResponse, error) {
+	return c.DB(name).ViewCleanup()
+}
+
+// EnsureFullCommit Commits the recent changes of the named database to disk.
+func (c *CouchCandy) EnsureFullCommit(name string) (*CommitResponse, error) {
+	return c.DB(name).EnsureFullCommit()
+}
+
+// GetRevsLimit Returns the revisions limit of the named database.
+func (c *CouchCandy) GetRevsLimit(name string) (int, error) {
+	return c.DB(name).GetRevsLimit()
+}
+
+// SetRevsLimit Sets the revisions limit of the named database.
+func (c *CouchCandy) SetRevsLimit(name string, limit int) (*OperationResponse, error) {
+	return c.DB(name).SetRevsLimit(limit)
+}
+
+// Purge Permanently removes the passed revisions from the named database.
+func (c *CouchCandy) Purge(name string, revisions map[string][]string) (*PurgeResponse, error) {
+	return c.DB(name).Purge(revisions)
+}
+
+// GetPurgedInfosLimit Returns the purged infos limit of the named database.
+func (c *CouchCandy) GetPurgedInfosLimit(name string) (int, error) {
+	return c.DB(name).GetPurgedInfosLimit()
+}
+
+// SetPurgedInfosLimit Sets the purged infos limit of the named database.
+func (c *CouchCandy) SetPurgedInfosLimit(name string, limit int) (*OperationResponse, error) {
+	return c.DB(name).SetPurgedInfosLimit(limit)
+}
+
+// GetSecurity Returns the security object of the named database.
+func (c *CouchCandy) GetSecurity(name string) (*Security, error) {
+	return c.DB(name).GetSecurity()
+}
+
+// SetSecurity Replaces the security object of the named database.
+func (c *CouchCandy) SetSecurity(name string, security *Security) (*OperationResponse, error) {
+	return c.DB(name).SetSecurity(security)
+}
+
+// AddAdminName Adds the user name to the admins of the named database.
+func (c *CouchCandy) AddAdminName(name, user string) (*OperationResponse, error) {
+	return c.DB(name).AddAdminName(user)
+}
+
+// RemoveAdminName Removes the user name from the admins of the named database.
+func (c *CouchCandy) RemoveAdminName(name, user string) (*OperationResponse, error) {
+	return c.DB(name).RemoveAdminName(user)
+}
+
+// AddAdminRole Adds the role to the admins of the named database.
+func (c *CouchCandy) AddAdminRole(name, role string) (*OperationResponse, error) {
+	return c.DB(name).AddAdminRole(role)
+}
+
+// RemoveAdminRole Removes the role from the admins of the named database.
+func (c *CouchCandy) RemoveAdminRole(name, role string) (*OperationResponse, error) {
+	return c.DB(name).RemoveAdminRole(role)
+}
+
+// AddMemberName Adds the user name to the members of the named database.
+func (c *CouchCandy) AddMemberName(name, user string) (*OperationResponse, error) {
+	return c.DB(name).AddMemberName(user)
+}
+
+// RemoveMemberName Removes the user name from the members of the named database.
+func (c *CouchCandy) RemoveMemberName(name, user string) (*OperationResponse, error) {
+	return c.DB(name).RemoveMemberName(user)
+}
+
+// AddMemberRole Adds the role to the members of the named database.
+func (c *CouchCandy) AddMemberRole(name, role string) (*OperationResponse, error) {
+	return c.DB(name).AddMemberRole(role)
+}
+
+// RemoveMemberRole Removes the role from the members of the named database.
+func (c *CouchCandy) RemoveMemberRole(name, role string) (*OperationResponse, error) {
+	return c.DB(name).RemoveMemberRole(role)
+}
+
+// AllDatabases : Returns all the database names in the system.
+func (c *CouchCandy) AllDatabases() ([]string, error) {
+	return c.Server().AllDatabases()
+}
+
+// AddDatabase : Creates a database in CouchDB. The session is left untouched,
+// use c.DB(name) to work with the new database.
+func (c *CouchCandy) AddDatabase(name string) (*OperationResponse, error) {
+	return c.Server().AddDatabase(name)
+}
+
+// DeleteDatabase : Deletes the passed database from the system.
+func (c *CouchCandy) DeleteDatabase(name string) (*OperationResponse, error) {
+	return c.Server().DeleteDatabase(name)
+}
+
+func (c *CouchCandy) sessionDB() *DB {
+	return c.DB(c.Session.Database)
+}
@@ -0,0 +1,157 @@
+package couchcandy
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+const (
+	// UsersDatabase is the name of the CouchDB authentication database
+	UsersDatabase string = "_users"
+	// userIDPrefix is the prefix of every user document id
+	userIDPrefix string = "org.couchdb.user:"
+)
+
+// User A document of the _users database. Password is only ever sent, CouchDB
+// replaces it by the derived key and salt when storing the document. These
+// fields are kept when reading a user so that updates preserve the credentials.
+type User struct {
+	CandyDocument
+	Name           string   `json:"name"`
+	Type           string   `json:"type"`
+	Roles          []string `json:"roles"`
+	Password       string   `json:"password,omitempty"`
+	PasswordScheme string   `json:"password_scheme,omitempty"`
+	Iterations     int      `json:"iterations,omitempty"`
+	DerivedKey     string   `json:"derived_key,omitempty"`
+	Salt           string   `json:"salt,omitempty"`
+}
+
+// Users Manages the users of the _users database.
+type Users struct {
+	db *DB
+}
+
+// Users Returns the API managing the users of the _users database.
+func (c *CouchCandy) Users() *Users {
+	return &Users{db: c.DB(UsersDatabase)}
+}
+
+// UserID Returns the document id of the passed user name.
+func UserID(name string) string {
+	return userIDPrefix + name
+}
+
+// Create Adds a user with the passed password and roles.
+func (u *Users) Create(name, password string, roles []string) (*OperationResponse, error) {
+
+	if roles == nil {
+		roles = make([]string, 0)
+	}
+
+	return u.db.AddWithID(UserID(name), &User{
+		Name:     name,
+		Type:     "user",
+		Roles:    roles,
+		Password: password,
+	})
+
+}
+
+// User Returns the user with the passed name.
+func (u *Users) User(name string) (*User, error) {
+
+	user := &User{}
+	if err := u.db.Document(UserID(name), user, Options{}); err != nil {
+		return nil, err
+	}
+
+	if user.Error != "" {
+		return nil, fmt.Errorf("%s: %s", user.Error, user.Reason)
+	}
+
+	return user, nil
+
+}
+
+// SetPassword Changes the password of the named user.
+func (u *Users) SetPassword(name, password string) (*OperationResponse, error) {
+	return u.update(name, func(user *User) {
+		user.Password = password
+	})
+}
+
+// SetRoles Replaces the roles of the named user.
+func (u *Users) SetRoles(name string, roles []string) (*OperationResponse, error) {
+
+	if roles == nil {
+		roles = make([]string, 0)
+	}
+
+	return u.update(name, func(user *User) {
+		user.Roles = roles
+	})
+
+}
+
+// List Returns all the users of the _users database.
+func (u *Users) List() ([]*User, error) {
+
+	allDocuments, err := u.db.Documents(Options{
+		StartKey:    fmt.Sprintf("%q", userIDPrefix),
+		EndKey:      fmt.Sprintf("%q", userIDPrefix+"\ufff0"),
+		IncludeDocs: true,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if allDocuments.Error != "" {
+		return nil, fmt.Errorf("%s: %s", allDocuments.Error, allDocuments.Reason)
+	}
+
+	users := make([]*User, 0, len(allDocuments.Rows))
+	for _, row := range allDocuments.Rows {
+
+		if !strings.HasPrefix(row.ID, userIDPrefix) {
+			continue
+		}
+
+		user := &User{}
+		if err = json.Unmarshal(row.Doc, user); err != nil {
+			return nil, err
+		}
+		users = append(users, user)
+
+	}
+
+	return users, nil
+
+}
+
+// Delete Removes the named user.
+func (u *Users) Delete(name string) (*OperationResponse, error) {
+
+	user, err := u.User(name)
+	if err != nil {
+		return nil, err
+	}
+
+	return u.db.DeleteDocument(user.ID, user.REV)
+
+}
+
+// update reads the named user, applies the change and writes the user back
+// with the revision it was read at.
+func (u *Users) update(name string, change func(*User)) (*OperationResponse, error) {
+
+	user, err := u.User(name)
+	if err != nil {
+		return nil, err
+	}
+
+	change(user)
+	return u.db.Update(user)
+
+}
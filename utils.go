@@ -5,19 +5,21 @@ import (
 	"encoding/json"
 	"fmt"
 	"net/url"
-	"strconv"
 	"strings"
 )
 
 func createBaseURL(session Session) string {
-	if strings.HasPrefix(session.Host, "https://") {
-		return fmt.Sprintf("https://%s:%s@%s:%s", session.Username, session.Password, session.Host[8:], strconv.Itoa(session.Port))
-	}
-	return fmt.Sprintf("http://%s:%s@%s:%s", session.Username, session.Password, session.Host[7:], strconv.Itoa(session.Port))
+	return baseURL(session).String()
+}
+
+// withDatabase returns a copy of the session pointing to the named database.
+func withDatabase(session Session, name string) Session {
+	session.Database = name
+	return session
 }
 
 func createDatabaseURL(session Session) string {
-	return fmt.Sprintf("%s/%s", createBaseURL(session), session.Database)
+	return fmt.Sprintf("%s/%s", createBaseURL(session), escapeSegment(session.Database))
 }
 
 func createPutDocumentURL(session Session, body string) string {
@@ -29,7 +31,21 @@ func createPutDocumentURL(session Session, body string) string {
 }
 
 func createDocumentURL(session Session, id string) string {
-	return fmt.Sprintf("%s/%s", createDatabaseURL(session), id)
+	return fmt.Sprintf("%s/%s", createDatabaseURL(session), escapeDocID(id))
+}
+
+func createLocalDocumentURL(session Session, id string) string {
+	return createDocumentURL(session, localPrefix+strings.TrimPrefix(id, localPrefix))
+}
+
+func createAttachmentURL(session Session, id, name string) string {
+	return fmt.Sprintf("%s/%s", createDocumentURL(session, id), escapeAttachmentName(name))
+}
+
+// createDesignDocURL returns the url of the design document followed by the
+// segments, see designDocPath.
+func createDesignDocURL(session Session, ddoc string, segments ...string) string {
+	return fmt.Sprintf("%s/%s", createDatabaseURL(session), designDocPath(ddoc, segments...))
 }
 
 func createDocumentURLWithOptions(session Session, id string, options Options) string {
@@ -67,6 +83,16 @@ func toOperationResponse(page []byte) (*OperationResponse, error) {
 	return response, err
 }
 
+// toCouchError returns the error described by the page when CouchDB answered
+// with an error object, nil otherwise.
+func toCouchError(page []byte) error {
+	response := &OperationResponse{}
+	if err := json.Unmarshal(page, response); err != nil || response.Error == "" {
+		return nil
+	}
+	return fmt.Errorf("%s: %s", response.Error, response.Reason)
+}
+
 // this is a violent hack to set the Revisions field to nil so that it does no get marshalled initially.
 func safeMarshall(document interface{}) (string, error) {
 	body, err := json.Marshal(document)
@@ -92,6 +118,25 @@ func toQueryString(options Options) string {
 
 }
 
+func toAllDatabasesQueryString(options Options) string {
+
+	parameters := []string{fmt.Sprintf("descending=%v", options.Descending)}
+	if options.Limit != 0 {
+		parameters = append(parameters, fmt.Sprintf("limit=%v", options.Limit))
+	}
+	if options.Skip != 0 {
+		parameters = append(parameters, fmt.Sprintf("skip=%v", options.Skip))
+	}
+	if options.StartKey != "" {
+		parameters = append(parameters, fmt.Sprintf("start_key=%s", url.QueryEscape(options.StartKey)))
+	}
+	if options.EndKey != "" {
+		parameters = append(parameters, fmt.Sprintf("end_key=%s", url.QueryEscape(options.EndKey)))
+	}
+	return "?" + strings.Join(parameters, "&")
+
+}
+
 func toParameters(options Options) []string {
 
 	parameters := make([]string, 0)
@@ -104,6 +149,9 @@ func toParameters(options Options) []string {
 	if options.Limit != 0 {
 		parameters = append(parameters, fmt.Sprintf("limit=%v", options.Limit))
 	}
+	if options.Skip != 0 {
+		parameters = append(parameters, fmt.Sprintf("skip=%v", options.Skip))
+	}
 	if options.Key != "" {
 		parameters = append(parameters, fmt.Sprintf("key=%s", url.QueryEscape(options.Key)))
 	}